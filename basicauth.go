@@ -0,0 +1,31 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+// probePaths stay reachable without credentials so that kubelet probes keep
+// working when basic auth is enabled.
+var probePaths = map[string]bool{"/healthz": true, "/readyz": true, "/ready": true, "/startup": true}
+
+func WrapWithBasicAuth(h http.Handler, user, password string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if probePaths[r.URL.Path] {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		gotUser, gotPassword, ok := r.BasicAuth()
+		// Both comparisons always run so the timing does not reveal which
+		// one failed.
+		userOK := subtle.ConstantTimeCompare([]byte(gotUser), []byte(user)) == 1
+		passwordOK := subtle.ConstantTimeCompare([]byte(gotPassword), []byte(password)) == 1
+		if !ok || !userOK || !passwordOK {
+			w.Header().Set("WWW-Authenticate", `Basic realm="demo"`)
+			writeJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBasicAuth(t *testing.T) {
+	handler := WrapWithBasicAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, StatusResponse{Status: "ok"})
+	}), "demo", "s3cret")
+
+	tests := []struct {
+		name     string
+		path     string
+		user     string
+		password string
+		setAuth  bool
+		want     int
+	}{
+		{"missing credentials", "/info", "", "", false, http.StatusUnauthorized},
+		{"wrong password", "/info", "demo", "wrong", true, http.StatusUnauthorized},
+		{"wrong user", "/info", "admin", "s3cret", true, http.StatusUnauthorized},
+		{"correct credentials", "/info", "demo", "s3cret", true, http.StatusOK},
+		{"healthz without credentials", "/healthz", "", "", false, http.StatusOK},
+		{"readyz without credentials", "/readyz", "", "", false, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.setAuth {
+				r.SetBasicAuth(tt.user, tt.password)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+			if tt.want == http.StatusUnauthorized {
+				if got := w.Header().Get("WWW-Authenticate"); got != `Basic realm="demo"` {
+					t.Errorf("WWW-Authenticate = %q", got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// writeBodyTooLarge also closes the connection, since the rest of the body
+// is never read.
+func writeBodyTooLarge(w http.ResponseWriter) {
+	w.Header().Set("Connection", "close")
+	writeJSON(w, http.StatusRequestEntityTooLarge, ErrorResponse{Error: "request body too large"})
+}
+
+// isBodyTooLarge reports whether err came from reading past the limit set by
+// WrapWithBodyLimit.
+func isBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+// BodyLimits is the maximum request body size, with per-path exceptions for
+// endpoints such as /upload that accept larger bodies.
+type BodyLimits struct {
+	Default int64
+	Paths   map[string]int64
+}
+
+func (l BodyLimits) forPath(path string) int64 {
+	if limit, ok := l.Paths[path]; ok {
+		return limit
+	}
+	return l.Default
+}
+
+// WrapWithBodyLimit rejects requests whose declared Content-Length exceeds
+// the limit for their path and caps the body of all others, so handlers
+// reading a chunked body get an error once they pass the limit. The body must
+// also arrive within readTimeout.
+func WrapWithBodyLimit(h http.Handler, limits BodyLimits, readTimeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		maxBytes := limits.forPath(r.URL.Path)
+		if r.ContentLength > maxBytes {
+			writeBodyTooLarge(w)
+			return
+		}
+
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			if readTimeout > 0 {
+				_ = http.NewResponseController(w).SetReadDeadline(time.Now().Add(readTimeout))
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
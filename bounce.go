@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var validBounceStatuses = map[int]bool{
+	http.StatusMovedPermanently:  true,
+	http.StatusFound:             true,
+	http.StatusTemporaryRedirect: true,
+	http.StatusPermanentRedirect: true,
+}
+
+// bounceAllowed reports whether target is an absolute http(s) URL whose host
+// is one of the allowed domains or a subdomain of one.
+func bounceAllowed(target *url.URL, allowedDomains []string) bool {
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return false
+	}
+
+	host := strings.ToLower(target.Hostname())
+	for _, domain := range allowedDomains {
+		domain = strings.ToLower(domain)
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// bounceHandler redirects to defaultURL, or to ?url= when that points at one
+// of allowedDomains, so the endpoint cannot be used as an open redirect.
+func bounceHandler(defaultURL string, status int, allowedDomains []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		target := defaultURL
+		if override := r.URL.Query().Get("url"); override != "" {
+			parsed, err := url.Parse(override)
+			if err != nil || !bounceAllowed(parsed, allowedDomains) {
+				writeJSON(w, http.StatusForbidden, ErrorResponse{Error: "redirect target not allowed"})
+				return
+			}
+			target = parsed.String()
+		}
+		if target == "" {
+			http.Error(w, "url is required when BOUNCE_URL is not set", http.StatusBadRequest)
+			return
+		}
+
+		http.Redirect(w, r, target, status)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	infoCacheHits   atomic.Int64
+	infoCacheMisses atomic.Int64
+)
+
+// ResponseCache keeps the last ServerInfo for ttl so that frequent /info
+// polling does not re-read interfaces and cgroup files on every request.
+type ResponseCache struct {
+	ttl time.Duration
+
+	mu      sync.RWMutex
+	info    ServerInfo
+	fetched time.Time
+}
+
+func NewResponseCache(ttl time.Duration) *ResponseCache {
+	return &ResponseCache{ttl: ttl}
+}
+
+// Get returns the cached ServerInfo and its age, refreshing it with fetch
+// once the TTL has passed. A zero TTL disables caching.
+func (c *ResponseCache) Get(fetch func() ServerInfo) (ServerInfo, time.Duration, bool) {
+	if c.ttl <= 0 {
+		return fetch(), 0, false
+	}
+
+	c.mu.RLock()
+	info, fetched := c.info, c.fetched
+	c.mu.RUnlock()
+	if age := time.Since(fetched); !fetched.IsZero() && age < c.ttl {
+		infoCacheHits.Add(1)
+		return info, age, true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Another request may have refreshed the entry while we waited.
+	if age := time.Since(c.fetched); !c.fetched.IsZero() && age < c.ttl {
+		infoCacheHits.Add(1)
+		return c.info, age, true
+	}
+	infoCacheMisses.Add(1)
+	c.info, c.fetched = fetch(), time.Now()
+	return c.info, 0, false
+}
+
+// ServerInfoCache holds the static part of ServerInfo so that /info does not
+// repeat the hostname, interface and cgroup lookups on every request.
+type ServerInfoCache struct {
+	fetch func() ServerInfo
+
+	mu   sync.RWMutex
+	info ServerInfo
+}
+
+var serverInfoCache *ServerInfoCache
+
+func NewServerInfoCache(fetch func() ServerInfo) *ServerInfoCache {
+	return &ServerInfoCache{fetch: fetch, info: fetch()}
+}
+
+func (c *ServerInfoCache) Get() ServerInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.info
+}
+
+// RefreshEvery re-collects the static fields every interval until ctx is
+// cancelled.
+func (c *ServerInfoCache) RefreshEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info := c.fetch()
+			c.mu.Lock()
+			c.info = info
+			c.mu.Unlock()
+		}
+	}
+}
+
+func ageHeader(age time.Duration) string {
+	return strconv.Itoa(int(age.Seconds()))
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const certExpiryCheckInterval = time.Hour
+
+// CertExpiry tracks how many whole days remain before the serving
+// certificate expires.
+type CertExpiry struct {
+	leaf     func() *x509.Certificate
+	daysLeft atomic.Int64
+}
+
+// NewCertExpiry checks the certificate returned by leaf, which follows
+// rotation when ENABLE_CERT_ROTATION is set.
+func NewCertExpiry(leaf func() *x509.Certificate) *CertExpiry {
+	c := &CertExpiry{leaf: leaf}
+	c.check()
+	return c
+}
+
+func daysUntil(t time.Time) int {
+	return int(time.Until(t) / (24 * time.Hour))
+}
+
+func (c *CertExpiry) check() {
+	c.daysLeft.Store(int64(daysUntil(c.leaf().NotAfter)))
+}
+
+func (c *CertExpiry) DaysLeft() int {
+	return int(c.daysLeft.Load())
+}
+
+// CheckEvery recomputes the days left every interval until ctx is cancelled.
+func (c *CertExpiry) CheckEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.check()
+		}
+	}
+}
+
+type HealthzResponse struct {
+	Status               string `json:"status"`
+	TLSCertExpiresInDays *int   `json:"tls_cert_expires_in_days,omitempty"`
+}
+
+// healthzHandler reports "ok", or "warn" once fewer than warnDays remain on
+// the certificate and "critical" with a 503 below criticalDays. expiry is
+// nil when TLS is disabled.
+func healthzHandler(expiry *CertExpiry, warnDays, criticalDays int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if expiry == nil {
+			writeJSON(w, http.StatusOK, HealthzResponse{Status: "ok"})
+			return
+		}
+
+		days := expiry.DaysLeft()
+		resp := HealthzResponse{Status: "ok", TLSCertExpiresInDays: &days}
+		status := http.StatusOK
+		switch {
+		case days < criticalDays:
+			resp.Status = "critical"
+			status = http.StatusServiceUnavailable
+		case days < warnDays:
+			resp.Status = "warn"
+		}
+		writeJSON(w, status, resp)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// certCacheTTL bounds how long a loaded key pair is reused before the files
+// are read again, in case a change was missed by the watcher.
+const certCacheTTL = 60 * time.Second
+
+// CertReloader serves the key pair currently on disk, so that a rotated
+// certificate is picked up by new connections without a restart.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	leaf     *x509.Certificate
+	loadedAt time.Time
+}
+
+// NewCertReloader loads the key pair once so that a broken pair stops the
+// process at startup rather than failing every handshake.
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	c := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// load must be called with mu held once the reloader is in use.
+func (c *CertReloader) load() error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return err
+	}
+	c.cert, c.leaf, c.loadedAt = &cert, leaf, time.Now()
+	return nil
+}
+
+// current reloads the key pair once the cache has expired. While the files
+// are being replaced they may not match, in which case the previous pair is
+// kept and the next handshake tries again.
+func (c *CertReloader) current() (*tls.Certificate, *x509.Certificate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.loadedAt) >= certCacheTTL {
+		if err := c.load(); err != nil {
+			logger.Error("failed to reload TLS certificate", "path", c.certFile, "error", err.Error())
+		}
+	}
+	return c.cert, c.leaf
+}
+
+func (c *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert, _ := c.current()
+	return cert, nil
+}
+
+func (c *CertReloader) Leaf() *x509.Certificate {
+	_, leaf := c.current()
+	return leaf
+}
+
+func (c *CertReloader) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loadedAt = time.Time{}
+}
+
+// Watch invalidates the cache whenever the certificate or key changes, until
+// ctx is cancelled. As with CONFIG_FILE, the directories are watched because
+// Kubernetes replaces mounted secrets rather than writing to them.
+func (c *CertReloader) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	for _, dir := range []string{filepath.Dir(c.certFile), filepath.Dir(c.keyFile)} {
+		if err := watcher.Add(dir); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-watcher.Errors:
+			logger.Error("certificate watcher error", "error", err.Error())
+		case <-watcher.Events:
+			c.invalidate()
+		}
+	}
+}
+
+// readLeafCertificate parses the first certificate in certFile, for servers
+// that load the key pair once.
+func readLeafCertificate(certFile string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("no certificate found in " + certFile)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+type TLSCertInfo struct {
+	Subject       string   `json:"subject"`
+	Issuer        string   `json:"issuer"`
+	DNSNames      []string `json:"dns_names,omitempty"`
+	IPAddresses   []string `json:"ip_addresses,omitempty"`
+	SerialNumber  string   `json:"serial_number"`
+	NotBefore     string   `json:"not_before"`
+	NotAfter      string   `json:"not_after"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+// debugTLSHandler describes the certificate being served. Only the public
+// certificate is shown, never the key.
+func debugTLSHandler(leaf func() *x509.Certificate) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cert := leaf()
+		info := TLSCertInfo{
+			Subject:       cert.Subject.String(),
+			Issuer:        cert.Issuer.String(),
+			DNSNames:      cert.DNSNames,
+			SerialNumber:  cert.SerialNumber.String(),
+			NotBefore:     cert.NotBefore.UTC().Format(time.RFC3339),
+			NotAfter:      cert.NotAfter.UTC().Format(time.RFC3339),
+			ExpiresInDays: daysUntil(cert.NotAfter),
+		}
+		for _, ip := range cert.IPAddresses {
+			info.IPAddresses = append(info.IPAddresses, ip.String())
+		}
+		writeJSON(w, http.StatusOK, info)
+	}
+}
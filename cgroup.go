@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var containerIDPattern = regexp.MustCompile(`[0-9a-f]{64}`)
+
+func getContainerID() string {
+	f, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	return parseContainerID(f)
+}
+
+// parseContainerID extracts the container ID from /proc/self/cgroup content.
+// Lines look like "hierarchy-ID:controllers:path"; the memory and cpu
+// controllers are checked first, then the cgroup v2 unified hierarchy.
+func parseContainerID(r io.Reader) string {
+	unified := ""
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+
+		id := containerIDPattern.FindString(parts[2])
+		if id == "" {
+			continue
+		}
+
+		if parts[1] == "" {
+			unified = id
+			continue
+		}
+		for _, controller := range strings.Split(parts[1], ",") {
+			if controller == "memory" || controller == "cpu" {
+				return id
+			}
+		}
+	}
+
+	return unified
+}
+
+const cgroupRoot = "/sys/fs/cgroup"
+
+// cgroupV1Unlimited is the threshold above which a cgroup v1 limit is the
+// kernel's "no limit" sentinel rather than a real limit.
+const cgroupV1Unlimited = 1 << 62
+
+func readCgroupInt(path string) (int64, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+
+	value := strings.TrimSpace(string(data))
+	if value == "max" {
+		return -1, true
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// getCgroupMemory returns the memory limit (-1 when unlimited) and current
+// usage in bytes of the cgroup mounted at root, reading the cgroup v2 files
+// first and falling back to cgroup v1. Missing values are returned as 0.
+func getCgroupMemory(root string) (limit int64, current int64) {
+	if limit, ok := readCgroupInt(filepath.Join(root, "memory.max")); ok {
+		current, _ := readCgroupInt(filepath.Join(root, "memory.current"))
+		return limit, current
+	}
+
+	limit, _ = readCgroupInt(filepath.Join(root, "memory", "memory.limit_in_bytes"))
+	if limit >= cgroupV1Unlimited {
+		limit = -1
+	}
+	current, _ = readCgroupInt(filepath.Join(root, "memory", "memory.usage_in_bytes"))
+	return limit, current
+}
+
+// getCgroupCPUInfo returns the CPU limit in millicores (-1 when unlimited) and
+// the cumulative CPU time in microseconds of the cgroup mounted at root,
+// reading the cgroup v2 files first and falling back to cgroup v1. Missing
+// values are returned as 0.
+func getCgroupCPUInfo(root string) (limitMillicores int64, usageUsec int64) {
+	if data, err := os.ReadFile(filepath.Join(root, "cpu.max")); err == nil {
+		return parseCPUMax(string(data)), readCPUStatUsage(filepath.Join(root, "cpu.stat"))
+	}
+
+	quota, ok := readCgroupInt(filepath.Join(root, "cpu", "cpu.cfs_quota_us"))
+	period, _ := readCgroupInt(filepath.Join(root, "cpu", "cpu.cfs_period_us"))
+	switch {
+	case ok && quota < 0:
+		limitMillicores = -1
+	case quota > 0 && period > 0:
+		limitMillicores = quota * 1000 / period
+	}
+	if usageNsec, ok := readCgroupInt(filepath.Join(root, "cpuacct", "cpuacct.usage")); ok {
+		usageUsec = usageNsec / 1000
+	}
+	return limitMillicores, usageUsec
+}
+
+// parseCPUMax converts the "quota period" contents of cpu.max to millicores.
+func parseCPUMax(s string) int64 {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return 0
+	}
+	if fields[0] == "max" {
+		return -1
+	}
+
+	quota, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0
+	}
+	period, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil || period <= 0 {
+		return 0
+	}
+	return quota * 1000 / period
+}
+
+func readCPUStatUsage(path string) int64 {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), "usage_usec "); ok {
+			n, _ := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			return n
+		}
+	}
+	return 0
+}
+
+// cpuUsageSampler turns the cumulative CPU time of the cgroup into a usage
+// rate in millicores, averaged over the interval since the previous sample.
+type cpuUsageSampler struct {
+	mu         sync.Mutex
+	lastUsage  int64
+	lastTime   time.Time
+	millicores int64
+}
+
+var cpuUsage cpuUsageSampler
+
+// minCPUSampleInterval keeps back-to-back requests from computing a rate over
+// a window too short to be meaningful; they get the previous value instead.
+const minCPUSampleInterval = 100 * time.Millisecond
+
+func (s *cpuUsageSampler) observe(usageUsec int64, now time.Time) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if usageUsec <= 0 {
+		return 0
+	}
+	if s.lastTime.IsZero() {
+		s.lastUsage, s.lastTime = usageUsec, now
+		return 0
+	}
+
+	elapsed := now.Sub(s.lastTime)
+	if elapsed < minCPUSampleInterval {
+		return s.millicores
+	}
+	s.millicores = (usageUsec - s.lastUsage) * 1000 / elapsed.Microseconds()
+	s.lastUsage, s.lastTime = usageUsec, now
+	return s.millicores
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const fixtureContainerID = "3f1c8b0a9d2e4f5a6b7c8d9e0f1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c"
+
+func TestParseContainerID(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    string
+	}{
+		{"testdata/cgroup-v1", fixtureContainerID},
+		{"testdata/cgroup-v2", fixtureContainerID},
+		{"testdata/cgroup-host", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			f, err := os.Open(tt.fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			if got := parseContainerID(f); got != tt.want {
+				t.Errorf("parseContainerID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// writeCgroupFiles creates a fake cgroup filesystem under a temp directory
+// and returns its root.
+func writeCgroupFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestGetCgroupMemory(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       map[string]string
+		wantLimit   int64
+		wantCurrent int64
+	}{
+		{
+			name:        "v2 limited",
+			files:       map[string]string{"memory.max": "536870912\n", "memory.current": "104857600\n"},
+			wantLimit:   536870912,
+			wantCurrent: 104857600,
+		},
+		{
+			name:        "v2 unlimited",
+			files:       map[string]string{"memory.max": "max\n", "memory.current": "4096\n"},
+			wantLimit:   -1,
+			wantCurrent: 4096,
+		},
+		{
+			name: "v1 limited",
+			files: map[string]string{
+				"memory/memory.limit_in_bytes": "268435456\n",
+				"memory/memory.usage_in_bytes": "8192\n",
+			},
+			wantLimit:   268435456,
+			wantCurrent: 8192,
+		},
+		{
+			name: "v1 unlimited",
+			files: map[string]string{
+				"memory/memory.limit_in_bytes": "9223372036854771712\n",
+				"memory/memory.usage_in_bytes": "8192\n",
+			},
+			wantLimit:   -1,
+			wantCurrent: 8192,
+		},
+		{
+			name:  "missing",
+			files: map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit, current := getCgroupMemory(writeCgroupFiles(t, tt.files))
+			if limit != tt.wantLimit || current != tt.wantCurrent {
+				t.Errorf("getCgroupMemory() = %d, %d, want %d, %d", limit, current, tt.wantLimit, tt.wantCurrent)
+			}
+		})
+	}
+}
+
+func TestGetCgroupCPUInfo(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     map[string]string
+		wantLimit int64
+		wantUsage int64
+	}{
+		{
+			name: "v2 limited",
+			files: map[string]string{
+				"cpu.max":  "50000 100000\n",
+				"cpu.stat": "usage_usec 123456\nuser_usec 100000\nsystem_usec 23456\n",
+			},
+			wantLimit: 500,
+			wantUsage: 123456,
+		},
+		{
+			name:      "v2 unlimited",
+			files:     map[string]string{"cpu.max": "max 100000\n", "cpu.stat": "usage_usec 42\n"},
+			wantLimit: -1,
+			wantUsage: 42,
+		},
+		{
+			name:  "v2 malformed cpu.max",
+			files: map[string]string{"cpu.max": "garbage\n"},
+		},
+		{
+			name: "v1 limited",
+			files: map[string]string{
+				"cpu/cpu.cfs_quota_us":  "200000\n",
+				"cpu/cpu.cfs_period_us": "100000\n",
+				"cpuacct/cpuacct.usage": "5000000\n",
+			},
+			wantLimit: 2000,
+			wantUsage: 5000,
+		},
+		{
+			name: "v1 unlimited",
+			files: map[string]string{
+				"cpu/cpu.cfs_quota_us":  "-1\n",
+				"cpu/cpu.cfs_period_us": "100000\n",
+			},
+			wantLimit: -1,
+		},
+		{
+			name:  "missing",
+			files: map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit, usage := getCgroupCPUInfo(writeCgroupFiles(t, tt.files))
+			if limit != tt.wantLimit || usage != tt.wantUsage {
+				t.Errorf("getCgroupCPUInfo() = %d, %d, want %d, %d", limit, usage, tt.wantLimit, tt.wantUsage)
+			}
+		})
+	}
+}
+
+func TestParseCPUMax(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"100000 100000", 1000},
+		{"150000 100000\n", 1500},
+		{"max 100000", -1},
+		{"100000 0", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := parseCPUMax(tt.in); got != tt.want {
+			t.Errorf("parseCPUMax(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
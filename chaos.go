@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type DelayConfig struct {
+	Default time.Duration
+	Max     time.Duration
+}
+
+func newDelayConfig(c Config) *DelayConfig {
+	return &DelayConfig{
+		Default: time.Duration(c.ResponseDelayMS) * time.Millisecond,
+		Max:     time.Duration(c.MaxDelayMS) * time.Millisecond,
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// applyDelay sleeps for the configured response delay, or for ?delay=N
+// milliseconds capped at the configured maximum. It returns false when the
+// request should not be answered, either because ?delay= is invalid or
+// because the client went away while waiting.
+func applyDelay(w http.ResponseWriter, r *http.Request, config DelayConfig) bool {
+	delay := config.Default
+	if delayParam := r.URL.Query().Get("delay"); delayParam != "" {
+		ms, err := strconv.Atoi(delayParam)
+		if err != nil || ms < 0 {
+			http.Error(w, "delay must be a non-negative number of milliseconds", http.StatusBadRequest)
+			return false
+		}
+		delay = time.Duration(ms) * time.Millisecond
+		if delay > config.Max {
+			delay = config.Max
+		}
+	}
+
+	return sleepContext(r.Context(), delay) == nil
+}
+
+type FaultInjector struct {
+	mu   sync.Mutex
+	rate float64
+	rnd  *rand.Rand
+}
+
+func NewFaultInjector(rate float64, src rand.Source) *FaultInjector {
+	injectedErrorRate.Set(rate)
+	return &FaultInjector{rate: rate, rnd: rand.New(src)}
+}
+
+func (f *FaultInjector) ShouldFail() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.rate <= 0 {
+		return false
+	}
+	return f.rnd.Float64() < f.rate
+}
+
+func (f *FaultInjector) SetRate(rate float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rate = rate
+	injectedErrorRate.Set(rate)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestApplyDelay(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		config DelayConfig
+		want   time.Duration
+	}{
+		{"default", "", DelayConfig{Default: 100 * time.Millisecond, Max: time.Second}, 100 * time.Millisecond},
+		{"query overrides default", "?delay=200", DelayConfig{Default: 100 * time.Millisecond, Max: time.Second}, 200 * time.Millisecond},
+		{"query capped at max", "?delay=5000", DelayConfig{Max: 150 * time.Millisecond}, 150 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/info"+tt.query, nil)
+
+			start := time.Now()
+			if !applyDelay(w, r, tt.config) {
+				t.Fatalf("applyDelay returned false, status %d", w.Code)
+			}
+			elapsed := time.Since(start)
+			if elapsed < tt.want || elapsed > tt.want*12/10 {
+				t.Errorf("delay = %v, want %v within 20%%", elapsed, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyDelayInvalid(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/info?delay=-1", nil)
+
+	if applyDelay(w, r, DelayConfig{Max: time.Second}) {
+		t.Fatal("applyDelay returned true for a negative delay")
+	}
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+// stepSource is a rand.Source whose Float64 values cycle through 0, 0.1,
+// 0.2 ... 0.9, so that the failure rate over ten calls is exact.
+type stepSource struct{ n int64 }
+
+func (s *stepSource) Int63() int64 {
+	v := s.n % 10 * (1 << 63 / 10)
+	s.n++
+	return v
+}
+
+func (s *stepSource) Seed(int64) {}
+
+func TestFaultInjectorRate(t *testing.T) {
+	tests := []struct {
+		rate float64
+		want int
+	}{
+		{0, 0},
+		{0.3, 30},
+		{0.55, 60},
+		{1, 100},
+	}
+	for _, tt := range tests {
+		faults := NewFaultInjector(tt.rate, &stepSource{})
+		failures := 0
+		for i := 0; i < 100; i++ {
+			if faults.ShouldFail() {
+				failures++
+			}
+		}
+		if failures != tt.want {
+			t.Errorf("rate %v: %d failures in 100 calls, want %d", tt.rate, failures, tt.want)
+		}
+	}
+}
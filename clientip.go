@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type ClientIPKey struct{}
+
+func clientIPFromContext(ctx context.Context) string {
+	clientIP, _ := ctx.Value(ClientIPKey{}).(string)
+	return clientIP
+}
+
+func isTrustedProxy(ip string, trusted []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the address of the original client. X-Forwarded-For and
+// X-Real-IP are only honoured when the direct peer is a trusted proxy, and
+// only if they carry a valid IP.
+func clientIP(r *http.Request, trusted []netip.Prefix) string {
+	peer := remoteIP(r)
+	if !isTrustedProxy(peer, trusted) {
+		return peer
+	}
+
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		first, _, _ := strings.Cut(xff, ",")
+		if addr, err := netip.ParseAddr(strings.TrimSpace(first)); err == nil {
+			return addr.Unmap().String()
+		}
+	}
+	if addr, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+		return addr.Unmap().String()
+	}
+	return peer
+}
+
+func WrapWithClientIP(h http.Handler, trusted []netip.Prefix) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), ClientIPKey{}, clientIP(r, trusted))
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,499 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds every setting read from the environment. It is loaded once at
+// startup and shared by all components.
+type Config struct {
+	Port        string   `json:"port"`
+	PortSet     bool     `json:"-"`
+	ListenAddrs []string `json:"listen_addrs,omitempty"`
+	MetricsPort string   `json:"metrics_port,omitempty"`
+	DebugPort   string   `json:"debug_port,omitempty"`
+	UpstreamURL string   `json:"upstream_url,omitempty"`
+
+	HeadlessServiceDNS   string `json:"headless_service_dns,omitempty"`
+	PeersCacheTTLSeconds int    `json:"peers_cache_ttl_seconds"`
+
+	UnixSocketPath string   `json:"unix_socket_path,omitempty"`
+	UnixSocketMode fileMode `json:"unix_socket_mode,omitempty"`
+
+	TLSEnabled         bool   `json:"tls_enabled"`
+	TLSPort            string `json:"tls_port,omitempty"`
+	TLSCertFile        string `json:"tls_cert_file,omitempty"`
+	TLSKeyFile         string `json:"-"`
+	MTLSCAFile         string `json:"mtls_ca_file,omitempty"`
+	EnableCertRotation bool   `json:"enable_cert_rotation"`
+	CertWarnDays       int    `json:"cert_warn_days"`
+	CertCriticalDays   int    `json:"cert_critical_days"`
+
+	BasicAuthUser     string `json:"basic_auth_user,omitempty"`
+	BasicAuthPassword string `json:"-"`
+
+	ShutdownTimeoutSeconds int `json:"shutdown_timeout_seconds"`
+	RequestTimeoutMS       int `json:"request_timeout_ms"`
+	ReadyDelaySeconds      int `json:"ready_delay_seconds"`
+	StartupDelaySeconds    int `json:"startup_delay_seconds"`
+
+	ResponseDelayMS int     `json:"response_delay_ms"`
+	MaxDelayMS      int     `json:"max_delay_ms"`
+	ErrorRate       float64 `json:"error_rate"`
+	InfoCacheTTLMS  int     `json:"info_cache_ttl_ms"`
+
+	InfoRefreshIntervalSeconds int `json:"info_refresh_interval_seconds"`
+
+	RateLimitRPS   float64 `json:"rate_limit_rps"`
+	RateLimitBurst int     `json:"rate_limit_burst"`
+
+	CORSAllowedOrigins []string `json:"cors_allowed_origins"`
+	CORSAllowedMethods string   `json:"cors_allowed_methods"`
+	CORSAllowedHeaders string   `json:"cors_allowed_headers"`
+
+	TrustedProxies []netip.Prefix `json:"trusted_proxies"`
+
+	GzipEnabled bool `json:"gzip_enabled"`
+	GzipLevel   int  `json:"gzip_level"`
+
+	LogFormat             string   `json:"log_format"`
+	LogLevel              string   `json:"log_level"`
+	OTLPEndpoint          string   `json:"otlp_endpoint,omitempty"`
+	IncludeLinkLocal      bool     `json:"include_link_local"`
+	DiskStatPath          string   `json:"disk_stat_path"`
+	MaxRequestBodyBytes   int      `json:"max_request_body_bytes"`
+	EchoMaxBodyBytes      int      `json:"echo_max_body_bytes"`
+	MaxMindDBPath         string   `json:"maxmind_db_path,omitempty"`
+	RedactHeaders         []string `json:"redact_headers"`
+	BounceURL             string   `json:"bounce_url,omitempty"`
+	BounceStatus          int      `json:"bounce_status"`
+	BounceAllowedDomains  []string `json:"bounce_allowed_domains,omitempty"`
+	EventIntervalSeconds  int      `json:"event_interval_seconds"`
+	WSMaxConnections      int      `json:"ws_max_connections"`
+	TemplatePath          string   `json:"template_path,omitempty"`
+	ResponseTemplate      string   `json:"response_template,omitempty"`
+	StressMaxConcurrent   int      `json:"stress_max_concurrent"`
+	IdempotencyCacheSize  int      `json:"idempotency_cache_size"`
+	IdempotencyTTLSeconds int      `json:"idempotency_ttl_seconds"`
+	HangMaxConnections    int      `json:"hang_max_connections"`
+	SlowMaxConnections    int      `json:"slow_max_connections"`
+	EnableLoadgen         bool     `json:"enable_loadgen"`
+	StressMaxMemoryMB     int      `json:"stress_max_memory_mb"`
+	DownloadMaxMB         int      `json:"download_max_mb"`
+	UploadMaxMB           int      `json:"upload_max_mb"`
+	DebugEndpoints        bool     `json:"debug_endpoints"`
+	EnableEnvEndpoint     bool     `json:"enable_env_endpoint"`
+	EnableMetricsEndpoint bool     `json:"enable_metrics_endpoint"`
+	EnableChaosEndpoints  bool     `json:"enable_chaos_endpoints"`
+	ChaosToken            string   `json:"-"`
+	EnvAllowlist          []string `json:"env_allowlist,omitempty"`
+	EnableEnvSet          bool     `json:"enable_env_set"`
+	MutableKeys           []string `json:"mutable_keys"`
+}
+
+var config Config
+
+// fileMode shows permissions in /config in their familiar octal form.
+type fileMode os.FileMode
+
+func (m fileMode) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%04o", uint32(m))), nil
+}
+
+func (m *fileMode) UnmarshalText(text []byte) error {
+	mode, err := strconv.ParseUint(string(text), 8, 32)
+	if err != nil || mode > 0o777 {
+		return fmt.Errorf("%q is not an octal permission such as 0660", text)
+	}
+	*m = fileMode(mode)
+	return nil
+}
+
+// UnmarshalJSON also accepts a number, which is what an unquoted YAML 0660
+// becomes on its way from the config file through JSON.
+func (m *fileMode) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		return m.UnmarshalText([]byte(text))
+	}
+	mode, err := strconv.ParseUint(string(data), 10, 32)
+	if err != nil || mode > 0o777 {
+		return fmt.Errorf("%s is not a permission such as 0660", data)
+	}
+	*m = fileMode(mode)
+	return nil
+}
+
+// defaultGzipLevel is what gzip.DefaultCompression maps to, spelled out so
+// that /config shows the effective level.
+const defaultGzipLevel = 6
+
+const defaultTrustedProxies = "10.0.0.0/8,172.16.0.0/12,192.168.0.0/16"
+
+// envParser reads typed settings from the environment. A variable that is
+// set but does not parse is recorded in errs and its fallback is used, so
+// that loadConfig can report every bad value at once.
+type envParser struct {
+	errs []error
+}
+
+func (p *envParser) Int(key string, fallback int) int {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || n < 0 {
+		p.errs = append(p.errs, fmt.Errorf("invalid %s %q: must be a non-negative integer", key, value))
+		return fallback
+	}
+	return n
+}
+
+func (p *envParser) Float(key string, fallback float64) float64 {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || f < 0 || math.IsNaN(f) || math.IsInf(f, 0) {
+		p.errs = append(p.errs, fmt.Errorf("invalid %s %q: must be a non-negative number", key, value))
+		return fallback
+	}
+	return f
+}
+
+func envString(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func (p *envParser) Bool(key string, fallback bool) bool {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	b, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		p.errs = append(p.errs, fmt.Errorf("invalid %s %q: must be true or false", key, value))
+		return fallback
+	}
+	return b
+}
+
+func splitList(s string) []string {
+	items := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// defaultConfig returns the settings used when neither CONFIG_FILE nor the
+// environment provides a value. Port is left empty so that loadConfig can
+// tell whether it was set explicitly.
+func defaultConfig() Config {
+	c := Config{
+		PeersCacheTTLSeconds: 10,
+		UnixSocketMode:       0o660,
+		CertWarnDays:         30,
+		CertCriticalDays:     7,
+		TLSPort:              "8443",
+
+		ShutdownTimeoutSeconds: 15,
+		RequestTimeoutMS:       30000,
+		MaxDelayMS:             5000,
+
+		InfoRefreshIntervalSeconds: 60,
+		RateLimitRPS:               10,
+		RateLimitBurst:             20,
+
+		CORSAllowedOrigins: []string{"*"},
+		CORSAllowedMethods: "GET,OPTIONS",
+		CORSAllowedHeaders: "Content-Type",
+
+		GzipEnabled: true,
+		GzipLevel:   defaultGzipLevel,
+
+		LogFormat:             "json",
+		LogLevel:              "info",
+		DiskStatPath:          "/",
+		MaxRequestBodyBytes:   1 << 20,
+		EchoMaxBodyBytes:      65536,
+		RedactHeaders:         []string{"Authorization", "Cookie"},
+		BounceStatus:          http.StatusFound,
+		EventIntervalSeconds:  5,
+		WSMaxConnections:      100,
+		StressMaxConcurrent:   1,
+		IdempotencyCacheSize:  1000,
+		IdempotencyTTLSeconds: 300,
+		HangMaxConnections:    5,
+		SlowMaxConnections:    10,
+		StressMaxMemoryMB:     512,
+		DownloadMaxMB:         100,
+		UploadMaxMB:           10,
+		MutableKeys:           []string{"RESPONSE_DELAY_MS", "ERROR_RATE", "LOG_LEVEL"},
+	}
+	for _, cidr := range splitList(defaultTrustedProxies) {
+		c.TrustedProxies = append(c.TrustedProxies, netip.MustParsePrefix(cidr))
+	}
+	return c
+}
+
+// configFileSecrets holds the file keys for settings that /config leaves
+// out, alongside the keys it does show.
+type configFileSecrets struct {
+	TLSKeyFile        string `json:"tls_key_file"`
+	BasicAuthPassword string `json:"basic_auth_password"`
+	ChaosToken        string `json:"chaos_token"`
+}
+
+// readConfigFile overlays the YAML or JSON file at path onto the defaults.
+// Keys are the same as in /config, plus tls_key_file, basic_auth_password
+// and chaos_token; YAML is converted to JSON first so the json tags on
+// Config are the single source of field names.
+func readConfigFile(path string) (Config, error) {
+	c := defaultConfig()
+	if path == "" {
+		return c, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return c, err
+	}
+	if raw == nil {
+		return c, nil
+	}
+
+	var secrets configFileSecrets
+	secretData, err := json.Marshal(raw)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(secretData, &secrets); err != nil {
+		return c, err
+	}
+	c.TLSKeyFile = secrets.TLSKeyFile
+	c.BasicAuthPassword = secrets.BasicAuthPassword
+	c.ChaosToken = secrets.ChaosToken
+	delete(raw, "tls_key_file")
+	delete(raw, "basic_auth_password")
+	delete(raw, "chaos_token")
+
+	jsonData, err := json.Marshal(raw)
+	if err != nil {
+		return c, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(jsonData))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&c); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+func envList(key string, fallback []string) []string {
+	if value, ok := os.LookupEnv(key); ok {
+		return splitList(value)
+	}
+	return fallback
+}
+
+// loadConfig builds the configuration from the defaults, then CONFIG_FILE,
+// then environment variables, each overriding the previous one.
+func loadConfig() (Config, error) {
+	configFile := os.Getenv("CONFIG_FILE")
+	file, err := readConfigFile(configFile)
+	if err != nil {
+		return file, fmt.Errorf("reading CONFIG_FILE %s: %w", configFile, err)
+	}
+
+	env := &envParser{}
+	c := Config{
+		Port:        envString("PORT", file.Port),
+		PortSet:     os.Getenv("PORT") != "" || file.Port != "",
+		ListenAddrs: envList("LISTEN_ADDRS", file.ListenAddrs),
+		MetricsPort: envString("METRICS_PORT", file.MetricsPort),
+		DebugPort:   envString("DEBUG_PORT", file.DebugPort),
+		UpstreamURL: envString("UPSTREAM_URL", file.UpstreamURL),
+
+		HeadlessServiceDNS:   envString("HEADLESS_SERVICE_DNS", file.HeadlessServiceDNS),
+		PeersCacheTTLSeconds: env.Int("PEERS_CACHE_TTL_SECONDS", file.PeersCacheTTLSeconds),
+
+		UnixSocketPath: envString("UNIX_SOCKET_PATH", file.UnixSocketPath),
+		UnixSocketMode: file.UnixSocketMode,
+
+		TLSPort:            envString("TLS_PORT", file.TLSPort),
+		TLSCertFile:        envString("HTTPS_CERT_FILE", file.TLSCertFile),
+		TLSKeyFile:         envString("HTTPS_KEY_FILE", file.TLSKeyFile),
+		MTLSCAFile:         envString("MTLS_CA_FILE", file.MTLSCAFile),
+		EnableCertRotation: env.Bool("ENABLE_CERT_ROTATION", file.EnableCertRotation),
+		CertWarnDays:       env.Int("CERT_WARN_DAYS", file.CertWarnDays),
+		CertCriticalDays:   env.Int("CERT_CRITICAL_DAYS", file.CertCriticalDays),
+
+		BasicAuthUser:     envString("BASIC_AUTH_USER", file.BasicAuthUser),
+		BasicAuthPassword: envString("BASIC_AUTH_PASSWORD", file.BasicAuthPassword),
+
+		ShutdownTimeoutSeconds: env.Int("SHUTDOWN_TIMEOUT_SECONDS", file.ShutdownTimeoutSeconds),
+		RequestTimeoutMS:       env.Int("REQUEST_TIMEOUT_MS", file.RequestTimeoutMS),
+		ReadyDelaySeconds:      env.Int("READY_DELAY_SECONDS", file.ReadyDelaySeconds),
+		StartupDelaySeconds:    env.Int("STARTUP_DELAY_SECONDS", file.StartupDelaySeconds),
+
+		ResponseDelayMS: env.Int("RESPONSE_DELAY_MS", file.ResponseDelayMS),
+		MaxDelayMS:      env.Int("MAX_DELAY_MS", file.MaxDelayMS),
+		ErrorRate:       env.Float("ERROR_RATE", file.ErrorRate),
+		InfoCacheTTLMS:  env.Int("INFO_CACHE_TTL_MS", file.InfoCacheTTLMS),
+
+		InfoRefreshIntervalSeconds: env.Int("INFO_REFRESH_INTERVAL_SECONDS", file.InfoRefreshIntervalSeconds),
+
+		RateLimitRPS:   env.Float("RATE_LIMIT_RPS", file.RateLimitRPS),
+		RateLimitBurst: env.Int("RATE_LIMIT_BURST", file.RateLimitBurst),
+
+		CORSAllowedOrigins: envList("CORS_ALLOWED_ORIGINS", file.CORSAllowedOrigins),
+		CORSAllowedMethods: envString("CORS_ALLOWED_METHODS", file.CORSAllowedMethods),
+		CORSAllowedHeaders: envString("CORS_ALLOWED_HEADERS", file.CORSAllowedHeaders),
+
+		TrustedProxies: file.TrustedProxies,
+
+		GzipEnabled: env.Bool("GZIP_ENABLED", file.GzipEnabled),
+		GzipLevel:   env.Int("GZIP_LEVEL", file.GzipLevel),
+
+		LogFormat:             envString("LOG_FORMAT", file.LogFormat),
+		LogLevel:              envString("LOG_LEVEL", file.LogLevel),
+		OTLPEndpoint:          envString("OTEL_EXPORTER_OTLP_ENDPOINT", file.OTLPEndpoint),
+		IncludeLinkLocal:      env.Bool("INCLUDE_LINK_LOCAL", file.IncludeLinkLocal),
+		DiskStatPath:          envString("DISK_STAT_PATH", file.DiskStatPath),
+		MaxRequestBodyBytes:   env.Int("MAX_REQUEST_BODY_BYTES", file.MaxRequestBodyBytes),
+		EchoMaxBodyBytes:      env.Int("ECHO_MAX_BODY_BYTES", file.EchoMaxBodyBytes),
+		MaxMindDBPath:         envString("MAXMIND_DB_PATH", file.MaxMindDBPath),
+		RedactHeaders:         envList("REDACT_HEADERS", file.RedactHeaders),
+		BounceURL:             envString("BOUNCE_URL", file.BounceURL),
+		BounceStatus:          env.Int("BOUNCE_STATUS", file.BounceStatus),
+		BounceAllowedDomains:  envList("BOUNCE_ALLOWED_DOMAINS", file.BounceAllowedDomains),
+		EventIntervalSeconds:  env.Int("EVENT_INTERVAL_SECONDS", file.EventIntervalSeconds),
+		WSMaxConnections:      env.Int("WS_MAX_CONNECTIONS", file.WSMaxConnections),
+		TemplatePath:          envString("TEMPLATE_PATH", file.TemplatePath),
+		ResponseTemplate:      envString("RESPONSE_TEMPLATE", file.ResponseTemplate),
+		StressMaxConcurrent:   env.Int("STRESS_MAX_CONCURRENT", file.StressMaxConcurrent),
+		IdempotencyCacheSize:  env.Int("IDEMPOTENCY_CACHE_SIZE", file.IdempotencyCacheSize),
+		IdempotencyTTLSeconds: env.Int("IDEMPOTENCY_TTL_SECONDS", file.IdempotencyTTLSeconds),
+		HangMaxConnections:    env.Int("SERVER_MAX_HANG_CONNECTIONS", file.HangMaxConnections),
+		SlowMaxConnections:    env.Int("SERVER_MAX_SLOW_CONNECTIONS", file.SlowMaxConnections),
+		EnableLoadgen:         env.Bool("ENABLE_LOADGEN", file.EnableLoadgen),
+		StressMaxMemoryMB:     env.Int("STRESS_MAX_MEMORY_MB", file.StressMaxMemoryMB),
+		DownloadMaxMB:         env.Int("DOWNLOAD_MAX_MB", file.DownloadMaxMB),
+		UploadMaxMB:           env.Int("UPLOAD_MAX_MB", file.UploadMaxMB),
+		DebugEndpoints:        env.Bool("DEBUG_ENDPOINTS", file.DebugEndpoints),
+		EnableEnvEndpoint:     env.Bool("ENABLE_ENV_ENDPOINT", file.EnableEnvEndpoint),
+		EnableMetricsEndpoint: env.Bool("ENABLE_METRICS_ENDPOINT", file.EnableMetricsEndpoint),
+		EnableChaosEndpoints:  env.Bool("ENABLE_CHAOS_ENDPOINTS", file.EnableChaosEndpoints),
+		ChaosToken:            envString("CHAOS_TOKEN", file.ChaosToken),
+		EnvAllowlist:          envList("ENV_ALLOWLIST", file.EnvAllowlist),
+		EnableEnvSet:          env.Bool("ENABLE_ENV_SET", file.EnableEnvSet),
+		MutableKeys:           envList("MUTABLE_KEYS", file.MutableKeys),
+	}
+	if err := errors.Join(env.errs...); err != nil {
+		return c, err
+	}
+	if c.Port == "" {
+		c.Port = "8080"
+	}
+
+	if value := os.Getenv("UNIX_SOCKET_MODE"); value != "" {
+		if err := c.UnixSocketMode.UnmarshalText([]byte(value)); err != nil {
+			return c, fmt.Errorf("invalid UNIX_SOCKET_MODE: %w", err)
+		}
+	}
+
+	if value, ok := os.LookupEnv("TRUSTED_PROXIES"); ok {
+		c.TrustedProxies = nil
+		for _, cidr := range splitList(value) {
+			prefix, err := netip.ParsePrefix(cidr)
+			if err != nil {
+				return c, fmt.Errorf("invalid TRUSTED_PROXIES entry %q: %w", cidr, err)
+			}
+			c.TrustedProxies = append(c.TrustedProxies, prefix)
+		}
+	}
+	for i, prefix := range c.TrustedProxies {
+		c.TrustedProxies[i] = prefix.Masked()
+	}
+
+	if c.EnableChaosEndpoints && c.ChaosToken == "" {
+		return c, errors.New("CHAOS_TOKEN must be set when ENABLE_CHAOS_ENDPOINTS is true")
+	}
+	if c.EnableEnvSet && c.ChaosToken == "" {
+		return c, errors.New("CHAOS_TOKEN must be set when ENABLE_ENV_SET is true")
+	}
+	if c.EnableEnvSet {
+		for _, key := range c.MutableKeys {
+			if _, ok := runtimeSettings[key]; !ok {
+				return c, fmt.Errorf("invalid MUTABLE_KEYS entry %q: only RESPONSE_DELAY_MS, MAX_DELAY_MS, ERROR_RATE, RATE_LIMIT_RPS, RATE_LIMIT_BURST and LOG_LEVEL can change at runtime", key)
+			}
+		}
+	}
+	if _, err := parseLogLevel(c.LogLevel); err != nil {
+		return c, fmt.Errorf("invalid LOG_LEVEL %q: %w", c.LogLevel, err)
+	}
+	if c.EventIntervalSeconds < 1 {
+		return c, fmt.Errorf("invalid EVENT_INTERVAL_SECONDS %d: must be at least 1", c.EventIntervalSeconds)
+	}
+	if c.InfoRefreshIntervalSeconds < 1 {
+		return c, fmt.Errorf("invalid INFO_REFRESH_INTERVAL_SECONDS %d: must be at least 1", c.InfoRefreshIntervalSeconds)
+	}
+	if c.RateLimitRPS <= 0 {
+		return c, fmt.Errorf("invalid RATE_LIMIT_RPS %v: must be greater than 0", c.RateLimitRPS)
+	}
+	if !validBounceStatuses[c.BounceStatus] {
+		return c, fmt.Errorf("invalid BOUNCE_STATUS %d: must be 301, 302, 307 or 308", c.BounceStatus)
+	}
+
+	if c.ErrorRate > 1 {
+		c.ErrorRate = 1
+	}
+	if c.GzipLevel > gzip.BestCompression {
+		c.GzipLevel = defaultGzipLevel
+	}
+
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return c, errors.New("HTTPS_CERT_FILE and HTTPS_KEY_FILE must be set together")
+	}
+	c.TLSEnabled = c.TLSCertFile != ""
+
+	if (c.BasicAuthUser == "") != (c.BasicAuthPassword == "") {
+		return c, errors.New("BASIC_AUTH_USER and BASIC_AUTH_PASSWORD must be set together")
+	}
+	if !c.TLSEnabled {
+		c.TLSPort = ""
+	}
+
+	return c, nil
+}
+
+func configHandler(w http.ResponseWriter, r *http.Request) {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	writeJSON(w, http.StatusOK, config)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigRateLimitRPS(t *testing.T) {
+	tests := []struct {
+		value   string
+		wantErr string
+	}{
+		{"0.5", ""},
+		{"0", "invalid RATE_LIMIT_RPS 0: must be greater than 0"},
+		{"-1", `invalid RATE_LIMIT_RPS "-1": must be a non-negative number`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("RATE_LIMIT_RPS", tt.value)
+			_, err := loadConfig()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("loadConfig() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("loadConfig() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadConfigFileUnixSocketMode(t *testing.T) {
+	tests := []struct {
+		yaml    string
+		want    fileMode
+		wantErr bool
+	}{
+		{"unix_socket_mode: 0600", 0o600, false},
+		{"unix_socket_mode: 0o640", 0o640, false},
+		{`unix_socket_mode: "0660"`, 0o660, false},
+		{"unix_socket_mode: 01000", 0, true},
+		{"unix_socket_mode: rw", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.yaml, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml+"\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			c, err := readConfigFile(path)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("readConfigFile() = %04o, want an error", uint32(c.UnixSocketMode))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readConfigFile() = %v", err)
+			}
+			if c.UnixSocketMode != tt.want {
+				t.Errorf("unix_socket_mode = %04o, want %04o", uint32(c.UnixSocketMode), uint32(tt.want))
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import "net/http"
+
+type CORSConfig struct {
+	AllowedOrigins []string
+	AllowedMethods string
+	AllowedHeaders string
+}
+
+func (c CORSConfig) allowOrigin(origin string) string {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" {
+			return "*"
+		}
+		if allowed == origin {
+			return origin
+		}
+	}
+	return ""
+}
+
+func WrapWithCORS(h http.Handler, config CORSConfig) http.Handler {
+	if len(config.AllowedOrigins) == 0 {
+		return h
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if allowed := config.allowOrigin(origin); allowed != "" {
+			if allowed != "*" {
+				w.Header().Add("Vary", "Origin")
+			}
+			w.Header().Set("Access-Control-Allow-Origin", allowed)
+			w.Header().Set("Access-Control-Allow-Methods", config.AllowedMethods)
+			w.Header().Set("Access-Control-Allow-Headers", config.AllowedHeaders)
+		}
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// gcMinInterval is how often /simulate/gc may force a collection.
+const gcMinInterval = 5 * time.Second
+
+type RuntimeStats struct {
+	Goroutines      int    `json:"goroutines"`
+	AllocBytes      uint64 `json:"alloc_bytes"`
+	TotalAllocBytes uint64 `json:"total_alloc_bytes"`
+	SysBytes        uint64 `json:"sys_bytes"`
+	HeapAllocBytes  uint64 `json:"heap_alloc_bytes"`
+	HeapSysBytes    uint64 `json:"heap_sys_bytes"`
+	GCCycles        uint32 `json:"gc_cycles"`
+	LastGC          string `json:"last_gc,omitempty"`
+}
+
+func getRuntimeStats() RuntimeStats {
+	// ReadMemStats stops the world briefly, which is fine for a debug endpoint.
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	stats := RuntimeStats{
+		Goroutines:      runtime.NumGoroutine(),
+		AllocBytes:      m.Alloc,
+		TotalAllocBytes: m.TotalAlloc,
+		SysBytes:        m.Sys,
+		HeapAllocBytes:  m.HeapAlloc,
+		HeapSysBytes:    m.HeapSys,
+		GCCycles:        m.NumGC,
+	}
+	if m.LastGC > 0 {
+		stats.LastGC = time.Unix(0, int64(m.LastGC)).UTC().Format(time.RFC3339)
+	}
+	return stats
+}
+
+func debugRuntimeHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, getRuntimeStats())
+}
+
+type GCResponse struct {
+	GCDurationMS    float64 `json:"gc_duration_ms"`
+	HeapBeforeBytes uint64  `json:"heap_before_bytes"`
+	HeapAfterBytes  uint64  `json:"heap_after_bytes"`
+	FreedBytes      int64   `json:"freed_bytes"`
+	NumGoroutines   int     `json:"num_goroutines"`
+}
+
+// simulateGCHandler runs a garbage collection and reports how long it took
+// and how much of the heap it freed. Calls less than gcMinInterval after the
+// previous one get 429, since every collection stops the world.
+func simulateGCHandler() http.HandlerFunc {
+	var mu sync.Mutex
+	var last time.Time
+	return func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		if wait := gcMinInterval - time.Since(last); !last.IsZero() && wait > 0 {
+			mu.Unlock()
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+		last = time.Now()
+		mu.Unlock()
+
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		start := time.Now()
+		runtime.GC()
+		elapsed := time.Since(start)
+		runtime.ReadMemStats(&after)
+
+		writeJSON(w, http.StatusOK, GCResponse{
+			GCDurationMS:    float64(elapsed.Microseconds()) / 1000,
+			HeapBeforeBytes: before.HeapAlloc,
+			HeapAfterBytes:  after.HeapAlloc,
+			FreedBytes:      int64(before.HeapAlloc) - int64(after.HeapAlloc),
+			NumGoroutines:   runtime.NumGoroutine(),
+		})
+	}
+}
+
+// pprofHandlers are the net/http/pprof endpoints. /debug/pprof/ also serves
+// the named profiles such as heap and goroutine.
+var pprofHandlers = map[string]http.HandlerFunc{
+	"/debug/pprof/":        pprof.Index,
+	"/debug/pprof/cmdline": pprof.Cmdline,
+	"/debug/pprof/profile": pprof.Profile,
+	"/debug/pprof/symbol":  pprof.Symbol,
+	"/debug/pprof/trace":   pprof.Trace,
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// gcGarbage gives the collection something to free.
+var gcGarbage [][]byte
+
+func TestSimulateGC(t *testing.T) {
+	handler := simulateGCHandler()
+	for i := 0; i < 64; i++ {
+		gcGarbage = append(gcGarbage, make([]byte, 64<<10))
+	}
+	gcGarbage = nil
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPost, "/simulate/gc", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	var resp GCResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.HeapAfterBytes > resp.HeapBeforeBytes {
+		t.Errorf("heap_after_bytes %d > heap_before_bytes %d", resp.HeapAfterBytes, resp.HeapBeforeBytes)
+	}
+	if want := int64(resp.HeapBeforeBytes) - int64(resp.HeapAfterBytes); resp.FreedBytes != want {
+		t.Errorf("freed_bytes = %d, want %d", resp.FreedBytes, want)
+	}
+	if resp.NumGoroutines <= 0 {
+		t.Errorf("num_goroutines = %d, want > 0", resp.NumGoroutines)
+	}
+
+	// A second call inside gcMinInterval is refused.
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPost, "/simulate/gc", nil))
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("second call status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("second call has no Retry-After header")
+	}
+}
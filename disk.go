@@ -0,0 +1,27 @@
+package main
+
+type DiskInfo struct {
+	Path        string  `json:"path" yaml:"path"`
+	TotalBytes  uint64  `json:"total_bytes" yaml:"total_bytes"`
+	UsedBytes   uint64  `json:"used_bytes" yaml:"used_bytes"`
+	FreeBytes   uint64  `json:"free_bytes" yaml:"free_bytes"`
+	UsedPercent float64 `json:"used_percent" yaml:"used_percent"`
+}
+
+func getDiskInfo(path string) *DiskInfo {
+	total, used, free, err := diskUsage(path)
+	if err != nil {
+		return nil
+	}
+
+	info := &DiskInfo{
+		Path:       path,
+		TotalBytes: total,
+		UsedBytes:  used,
+		FreeBytes:  free,
+	}
+	if total > 0 {
+		info.UsedPercent = float64(used) / float64(total) * 100
+	}
+	return info
+}
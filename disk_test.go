@@ -0,0 +1,35 @@
+//go:build linux || darwin || windows
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestGetDiskInfo(t *testing.T) {
+	dir := t.TempDir()
+
+	info := getDiskInfo(dir)
+	if info == nil {
+		t.Fatalf("getDiskInfo(%q) = nil", dir)
+	}
+	if info.Path != dir {
+		t.Errorf("Path = %q, want %q", info.Path, dir)
+	}
+	if info.TotalBytes == 0 {
+		t.Error("TotalBytes = 0")
+	}
+	if info.UsedBytes+info.FreeBytes > info.TotalBytes {
+		t.Errorf("used %d + free %d > total %d", info.UsedBytes, info.FreeBytes, info.TotalBytes)
+	}
+	if info.UsedPercent < 0 || info.UsedPercent > 100 {
+		t.Errorf("UsedPercent = %v, want between 0 and 100", info.UsedPercent)
+	}
+}
+
+func TestGetDiskInfoMissingPath(t *testing.T) {
+	if info := getDiskInfo(filepath.Join(t.TempDir(), "missing")); info != nil {
+		t.Errorf("getDiskInfo() = %+v, want nil", info)
+	}
+}
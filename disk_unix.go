@@ -0,0 +1,20 @@
+//go:build linux || darwin
+
+package main
+
+import "syscall"
+
+// diskUsage reports free space as the blocks available to unprivileged users,
+// so used+free can be less than total on filesystems with reserved blocks.
+func diskUsage(path string) (total, used, free uint64, err error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, 0, 0, err
+	}
+
+	blockSize := uint64(stat.Bsize)
+	total = stat.Blocks * blockSize
+	used = (stat.Blocks - stat.Bfree) * blockSize
+	free = stat.Bavail * blockSize
+	return total, used, free, nil
+}
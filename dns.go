@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	dnsLookupTimeout = 5 * time.Second
+	maxHostnameLen   = 253
+	maxCNAMEHops     = 5
+)
+
+type DNSAddress struct {
+	IPAddress string `json:"ip_address"`
+	IPFamily  string `json:"ip_family"`
+}
+
+type DNSResponse struct {
+	Host      string       `json:"host"`
+	Addresses []DNSAddress `json:"addresses"`
+	LatencyMS float64      `json:"latency_ms"`
+	Error     string       `json:"error,omitempty"`
+}
+
+// errTooManyCNAMEHops is reported for names whose CNAME chain is longer
+// than maxCNAMEHops.
+var errTooManyCNAMEHops = fmt.Errorf("CNAME chain longer than %d hops", maxCNAMEHops)
+
+// resolveCNAME follows the CNAME chain from host to its canonical name, one
+// LookupCNAME at a time. A resolver that answers with the end of the chain
+// straight away uses a single hop. Lookup errors end the walk, so that
+// LookupIPAddr reports them.
+func resolveCNAME(ctx context.Context, host string) (string, error) {
+	name := strings.TrimSuffix(host, ".")
+	for hops := 0; ; hops++ {
+		cname, err := net.DefaultResolver.LookupCNAME(ctx, name)
+		cname = strings.TrimSuffix(cname, ".")
+		if err != nil || cname == "" || strings.EqualFold(cname, name) {
+			return name, nil
+		}
+		if hops == maxCNAMEHops {
+			return "", errTooManyCNAMEHops
+		}
+		name = cname
+	}
+}
+
+// dnsHandler resolves ?host= with the system resolver, refusing names whose
+// CNAME chain is longer than maxCNAMEHops.
+func dnsHandler(w http.ResponseWriter, r *http.Request) {
+	host := r.URL.Query().Get("host")
+	if host == "" || len(host) > maxHostnameLen {
+		http.Error(w, "host must be between 1 and 253 characters", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), dnsLookupTimeout)
+	defer cancel()
+
+	start := time.Now()
+	var addrs []net.IPAddr
+	name, err := resolveCNAME(ctx, host)
+	if err == nil {
+		addrs, err = net.DefaultResolver.LookupIPAddr(ctx, name)
+	}
+	response := DNSResponse{
+		Host:      host,
+		Addresses: []DNSAddress{},
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		response.Error = err.Error()
+	}
+
+	for _, addr := range addrs {
+		response.Addresses = append(response.Addresses, DNSAddress{
+			IPAddress: addr.IP.String(),
+			IPFamily:  ipFamily(addr.IP),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"crypto/rand"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// downloadChunkSize is how much is written between checks for a client
+// that has gone away.
+const downloadChunkSize = 64 << 10
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func downloadHandler(maxMB int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sizeMB, err := queryInt(r, "size_mb", min(1, maxMB), 1, maxMB)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		random, _ := strconv.ParseBool(r.URL.Query().Get("random"))
+
+		var src io.Reader = zeroReader{}
+		if random {
+			src = rand.Reader
+		}
+
+		size := int64(sizeMB) << 20
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+		w.Header().Set("Content-Disposition", `attachment; filename="download.bin"`)
+
+		for remaining := size; remaining > 0; remaining -= downloadChunkSize {
+			if r.Context().Err() != nil {
+				return
+			}
+			if _, err := io.CopyN(w, src, min(remaining, downloadChunkSize)); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type EchoResponse struct {
+	Method        string                 `json:"method"`
+	URL           string                 `json:"url"`
+	Proto         string                 `json:"proto"`
+	Headers       map[string][]string    `json:"headers"`
+	MeshHeaders   map[string]http.Header `json:"mesh_headers,omitempty"`
+	RemoteAddr    string                 `json:"remote_addr"`
+	ClientIP      string                 `json:"client_ip"`
+	ClientGeo     *GeoInfo               `json:"client_geo,omitempty"`
+	XForwardedFor string                 `json:"x_forwarded_for,omitempty"`
+	XRealIP       string                 `json:"x_real_ip,omitempty"`
+	Body          string                 `json:"body"`
+	BodyEncoding  string                 `json:"body_encoding,omitempty"`
+}
+
+// meshHeaderGroup returns the mesh_headers group a lowercase header name
+// belongs to, or "" for ordinary headers.
+func meshHeaderGroup(name string) string {
+	switch {
+	case strings.HasPrefix(name, "l5d-"):
+		return "linkerd"
+	case strings.HasPrefix(name, "x-envoy-"), strings.HasPrefix(name, "x-istio-"):
+		return "istio"
+	case name == "b3", strings.HasPrefix(name, "x-b3-"):
+		return "b3"
+	}
+	return ""
+}
+
+// classifyHeaders picks out the service mesh and B3 tracing headers in h,
+// grouped by mesh. Groups without any matching header are omitted.
+func classifyHeaders(h http.Header) map[string]http.Header {
+	groups := map[string]http.Header{}
+	for name, values := range h {
+		group := meshHeaderGroup(strings.ToLower(name))
+		if group == "" {
+			continue
+		}
+		if groups[group] == nil {
+			groups[group] = http.Header{}
+		}
+		groups[group][name] = values
+	}
+	return groups
+}
+
+// echoHandler adds the client's location to the response unless geo is the
+// no-op geolocator.
+func echoHandler(maxBodyBytes int64, geo Geolocator) http.HandlerFunc {
+	_, noGeo := geo.(NoopGeolocator)
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes))
+		if isBodyTooLarge(err) {
+			writeBodyTooLarge(w)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Error reading request body", http.StatusBadRequest)
+			return
+		}
+
+		response := EchoResponse{
+			Method:        r.Method,
+			URL:           r.URL.String(),
+			Proto:         r.Proto,
+			Headers:       r.Header,
+			MeshHeaders:   classifyHeaders(r.Header),
+			RemoteAddr:    r.RemoteAddr,
+			ClientIP:      clientIPFromContext(r.Context()),
+			XForwardedFor: r.Header.Get("X-Forwarded-For"),
+			XRealIP:       r.Header.Get("X-Real-IP"),
+		}
+		if ip := net.ParseIP(response.ClientIP); ip != nil && !noGeo {
+			if location, err := geo.Locate(ip); err == nil {
+				response.ClientGeo = &location
+			}
+		}
+		response.Body, response.BodyEncoding = encodeBody(body)
+
+		writeJSON(w, http.StatusOK, response)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestClassifyHeaders(t *testing.T) {
+	tests := []struct {
+		name string
+		in   http.Header
+		want map[string]http.Header
+	}{
+		{
+			name: "no mesh headers",
+			in:   http.Header{"Accept": {"*/*"}, "User-Agent": {"curl"}},
+			want: map[string]http.Header{},
+		},
+		{
+			name: "linkerd",
+			in:   http.Header{"L5d-Dst-Override": {"web.default.svc:80"}, "Accept": {"*/*"}},
+			want: map[string]http.Header{"linkerd": {"L5d-Dst-Override": {"web.default.svc:80"}}},
+		},
+		{
+			name: "istio and envoy",
+			in: http.Header{
+				"X-Envoy-Upstream-Service-Time": {"3"},
+				"X-Envoy-Peer-Metadata":         {"Cg=="},
+				"X-Istio-Attributes":            {"a"},
+			},
+			want: map[string]http.Header{"istio": {
+				"X-Envoy-Upstream-Service-Time": {"3"},
+				"X-Envoy-Peer-Metadata":         {"Cg=="},
+				"X-Istio-Attributes":            {"a"},
+			}},
+		},
+		{
+			name: "b3",
+			in:   http.Header{"X-B3-Traceid": {"abc"}, "X-B3-Sampled": {"1"}, "B3": {"abc-def-1"}},
+			want: map[string]http.Header{"b3": {"X-B3-Traceid": {"abc"}, "X-B3-Sampled": {"1"}, "B3": {"abc-def-1"}}},
+		},
+		{
+			name: "lowercase names from HTTP/2",
+			in:   http.Header{"l5d-client-id": {"web"}, "x-b3-spanid": {"def"}},
+			want: map[string]http.Header{
+				"linkerd": {"l5d-client-id": {"web"}},
+				"b3":      {"x-b3-spanid": {"def"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyHeaders(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("classifyHeaders() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
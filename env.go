@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strings"
+)
+
+const redactedValue = "***REDACTED***"
+
+var sensitiveEnvNames = []string{"SECRET", "PASSWORD", "TOKEN", "KEY"}
+
+func isSensitiveEnvName(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, sensitive := range sensitiveEnvNames {
+		if strings.Contains(upper, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// safeEnviron returns the process environment with sensitive values redacted,
+// restricted to names starting with one of allowlist when it is not empty.
+func safeEnviron(environ []string, allowlist []string) map[string]string {
+	env := map[string]string{}
+	for _, entry := range environ {
+		name, value, _ := strings.Cut(entry, "=")
+		if len(allowlist) > 0 && !hasAnyPrefix(name, allowlist) {
+			continue
+		}
+		if isSensitiveEnvName(name) {
+			value = redactedValue
+		}
+		env[name] = value
+	}
+	return env
+}
+
+func envHandler(allowlist []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, safeEnviron(os.Environ(), allowlist))
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSafeEnvironRedacts(t *testing.T) {
+	environ := []string{
+		"DB_SECRET=s1",
+		"admin_password=p1",
+		"GithubToken=t1",
+		"API_KEY=k1",
+		"HOME=/root",
+		"EMPTY=",
+	}
+	want := map[string]string{
+		"DB_SECRET":      redactedValue,
+		"admin_password": redactedValue,
+		"GithubToken":    redactedValue,
+		"API_KEY":        redactedValue,
+		"HOME":           "/root",
+		"EMPTY":          "",
+	}
+
+	got := safeEnviron(environ, nil)
+	if len(got) != len(want) {
+		t.Fatalf("safeEnviron() = %v, want %v", got, want)
+	}
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("%s = %q, want %q", name, got[name], value)
+		}
+	}
+}
+
+func TestSafeEnvironAllowlist(t *testing.T) {
+	got := safeEnviron([]string{"K8S_POD_NAME=web-0", "K8S_TOKEN=t", "HOME=/root"}, []string{"K8S_"})
+	if len(got) != 2 || got["K8S_POD_NAME"] != "web-0" || got["K8S_TOKEN"] != redactedValue {
+		t.Errorf("safeEnviron() = %v", got)
+	}
+}
+
+func TestEnvSetRateLimitRPS(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	t.Setenv("RATE_LIMIT_RPS", "10")
+
+	var applied []float64
+	handler := envSetHandler([]string{"RATE_LIMIT_RPS"}, func(c Config) {
+		applied = append(applied, c.RateLimitRPS)
+	})
+	tests := []struct {
+		value  string
+		status int
+		env    string
+	}{
+		{"0", http.StatusBadRequest, "10"},
+		{"-1", http.StatusBadRequest, "10"},
+		{"abc", http.StatusBadRequest, "10"},
+		{"2.5", http.StatusOK, "2.5"},
+	}
+	for _, tt := range tests {
+		body := strings.NewReader(`{"key":"RATE_LIMIT_RPS","value":"` + tt.value + `"}`)
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodPost, "/env/set", body))
+
+		if w.Code != tt.status {
+			t.Errorf("RATE_LIMIT_RPS=%s: status = %d, want %d: %s", tt.value, w.Code, tt.status, w.Body)
+		}
+		if got := os.Getenv("RATE_LIMIT_RPS"); got != tt.env {
+			t.Errorf("RATE_LIMIT_RPS=%s: environment has %q, want %q", tt.value, got, tt.env)
+		}
+	}
+	if len(applied) != 1 || applied[0] != 2.5 {
+		t.Errorf("applied %v, want [2.5]", applied)
+	}
+}
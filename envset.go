@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type EnvVar struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// runtimeSetting is an environment variable whose setting is applied
+// without a restart. parse checks a new value against the setting's type,
+// and get reads the setting back from a loaded Config in the same form.
+type runtimeSetting struct {
+	parse func(string) (any, error)
+	get   func(Config) any
+}
+
+func intSetting(get func(Config) int) runtimeSetting {
+	return runtimeSetting{
+		parse: func(s string) (any, error) {
+			n, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil || n < 0 {
+				return nil, errors.New("must be a non-negative integer")
+			}
+			return n, nil
+		},
+		get: func(c Config) any { return get(c) },
+	}
+}
+
+func floatSetting(get func(Config) float64) runtimeSetting {
+	return runtimeSetting{
+		parse: func(s string) (any, error) {
+			f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			if err != nil || f < 0 {
+				return nil, errors.New("must be a non-negative number")
+			}
+			return f, nil
+		},
+		get: func(c Config) any { return get(c) },
+	}
+}
+
+// positiveFloatSetting is a floatSetting that also refuses 0, for rates where
+// zero would stop all traffic.
+func positiveFloatSetting(get func(Config) float64) runtimeSetting {
+	setting := floatSetting(get)
+	parse := setting.parse
+	setting.parse = func(s string) (any, error) {
+		f, err := parse(s)
+		if err == nil && f.(float64) == 0 {
+			return nil, errors.New("must be greater than 0")
+		}
+		return f, err
+	}
+	return setting
+}
+
+// runtimeSettings are the variables that /env/set can change, matching the
+// fields copied by applyReloadedConfig.
+var runtimeSettings = map[string]runtimeSetting{
+	"RESPONSE_DELAY_MS": intSetting(func(c Config) int { return c.ResponseDelayMS }),
+	"MAX_DELAY_MS":      intSetting(func(c Config) int { return c.MaxDelayMS }),
+	"ERROR_RATE":        floatSetting(func(c Config) float64 { return c.ErrorRate }),
+	"RATE_LIMIT_RPS":    positiveFloatSetting(func(c Config) float64 { return c.RateLimitRPS }),
+	"RATE_LIMIT_BURST":  intSetting(func(c Config) int { return c.RateLimitBurst }),
+	"LOG_LEVEL": {
+		parse: func(s string) (any, error) { return parseLogLevel(s) },
+		get: func(c Config) any {
+			level, _ := parseLogLevel(c.LogLevel)
+			return level
+		},
+	},
+}
+
+// envSetMu serialises /env/set requests, so that each one reloads the
+// configuration from the environment it just changed.
+var envSetMu sync.Mutex
+
+func restoreEnv(key, previous string, hadPrevious bool) {
+	if hadPrevious {
+		os.Setenv(key, previous)
+	} else {
+		os.Unsetenv(key)
+	}
+}
+
+// envSetHandler sets one of the mutable environment variables and reloads
+// the configuration from the environment, handing the result to apply. A
+// value that does not parse, makes the configuration invalid or is not
+// what the reloaded configuration ends up with is rolled back.
+func envSetHandler(mutableKeys []string, apply func(Config)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req EnvVar
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			if isBodyTooLarge(err) {
+				writeBodyTooLarge(w)
+				return
+			}
+			http.Error(w, "invalid JSON body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		setting, ok := runtimeSettings[req.Key]
+		if !ok || !slices.Contains(mutableKeys, req.Key) {
+			writeJSON(w, http.StatusForbidden, ErrorResponse{Error: req.Key + " is not in MUTABLE_KEYS"})
+			return
+		}
+		want, err := setting.parse(req.Value)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("invalid %s %q: %v", req.Key, req.Value, err)})
+			return
+		}
+
+		envSetMu.Lock()
+		defer envSetMu.Unlock()
+
+		previous, hadPrevious := os.LookupEnv(req.Key)
+		os.Setenv(req.Key, req.Value)
+		c, err := loadConfig()
+		if err != nil {
+			restoreEnv(req.Key, previous, hadPrevious)
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		if got := setting.get(c); got != want {
+			restoreEnv(req.Key, previous, hadPrevious)
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("%s=%s would be applied as %v", req.Key, req.Value, got)})
+			return
+		}
+		apply(c)
+
+		value := req.Value
+		if isSensitiveEnvName(req.Key) {
+			value, previous = redactedValue, redactedValue
+		}
+		logger.Warn("environment variable set", "key", req.Key, "value", value, "previous", previous)
+		writeJSON(w, http.StatusOK, EnvVar{Key: req.Key, Value: value})
+	}
+}
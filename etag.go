@@ -0,0 +1,58 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// infoETag returns a weak ETag for info as rendered in mediaType. Fields that
+// change on every request (uptime, request ID and counters, current CPU,
+// memory and disk usage) are left out of the hash so that pollers see 304 until
+// something meaningful moves.
+func infoETag(info ServerInfo, mediaType string) string {
+	info.UptimeSeconds = 0
+	info.ActiveRequests = 0
+	info.TotalRequests = 0
+	info.RequestID = ""
+	info.RequestCount = 0
+	info.MemoryCurrentBytes = 0
+	info.CPUUsageMillicores = 0
+	info.Disk = nil
+
+	payload, err := json.Marshal(info)
+	if err != nil {
+		return ""
+	}
+	sum := md5.Sum(append([]byte(mediaType+"\n"), payload...))
+	return `W/"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// etagMatches implements the weak comparison If-None-Match calls for.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkNotModified sets the ETag header and answers 304 when the client
+// already holds that version. It reports whether the response was written.
+func checkNotModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	if etag == "" {
+		return false
+	}
+
+	w.Header().Set("ETag", etag)
+	w.Header().Add("Vary", "Accept")
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" && etagMatches(ifNoneMatch, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInfoETagCycle(t *testing.T) {
+	info := ServerInfo{Hostname: "web-0"}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if checkNotModified(w, r, infoETag(info, "application/json")) {
+			return
+		}
+		writeJSON(w, http.StatusOK, info)
+	}
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/info", nil)
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+
+	first := get("")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("first response: status %d, ETag %q; want 200 with a weak ETag", first.Code, etag)
+	}
+
+	// Uptime is left out of the hash, so it alone does not change the ETag.
+	info.UptimeSeconds = 42
+	second := get(etag)
+	if second.Code != http.StatusNotModified || second.Body.Len() != 0 {
+		t.Fatalf("second response: status %d, body %q; want 304 with no body", second.Code, second.Body)
+	}
+
+	info.Hostname = "web-1"
+	third := get(etag)
+	if third.Code != http.StatusOK {
+		t.Fatalf("third response: status %d, want 200", third.Code)
+	}
+	if got := third.Header().Get("ETag"); got == etag {
+		t.Errorf("ETag did not change with the hostname: %q", got)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// InfoQuery is the optional JSON body of a POST to /info.
+type InfoQuery struct {
+	Fields []string `json:"fields"`
+}
+
+type UnknownFieldsResponse struct {
+	Error       string   `json:"error"`
+	ValidFields []string `json:"valid_fields"`
+}
+
+var infoFieldNames = jsonFieldNames(reflect.TypeOf(ServerInfo{}))
+
+// jsonFieldNames lists the top-level JSON keys of struct type t, flattening
+// embedded structs the way encoding/json does.
+func jsonFieldNames(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			names = append(names, jsonFieldNames(field.Type)...)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// readInfoQuery decodes the field selector from a JSON POST. It returns nil
+// for any other request, and for a POST without a body.
+func readInfoQuery(r *http.Request) (*InfoQuery, error) {
+	if r.Method != http.MethodPost {
+		return nil, nil
+	}
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		return nil, nil
+	}
+
+	var query InfoQuery
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &query, nil
+}
+
+// unknownInfoFields returns the requested names that are not ServerInfo keys.
+func unknownInfoFields(fields []string) []string {
+	var unknown []string
+	for _, name := range fields {
+		if !slices.Contains(infoFieldNames, name) {
+			unknown = append(unknown, name)
+		}
+	}
+	return unknown
+}
+
+// selectInfoFields renders only the named top-level fields of info. Fields
+// that are empty and tagged omitempty are left out, as in the full response.
+func selectInfoFields(info ServerInfo, fields []string) (map[string]json.RawMessage, error) {
+	payload, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &all); err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]json.RawMessage, len(fields))
+	for _, name := range fields {
+		if value, ok := all[name]; ok {
+			selected[name] = value
+		}
+	}
+	return selected, nil
+}
+
+func writeUnknownFields(w http.ResponseWriter, unknown []string) {
+	writeJSON(w, http.StatusBadRequest, UnknownFieldsResponse{
+		Error:       fmt.Sprintf("unknown fields: %s", strings.Join(unknown, ", ")),
+		ValidFields: infoFieldNames,
+	})
+}
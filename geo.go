@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+type GeoInfo struct {
+	Country string `json:"country"`
+	Region  string `json:"region"`
+	City    string `json:"city"`
+}
+
+// Geolocator maps a client address to a location.
+type Geolocator interface {
+	Locate(ip net.IP) (GeoInfo, error)
+}
+
+// NoopGeolocator is used when no geolocation database is configured.
+type NoopGeolocator struct{}
+
+func (NoopGeolocator) Locate(net.IP) (GeoInfo, error) {
+	return GeoInfo{}, nil
+}
+
+// MaxMindGeolocator looks addresses up in a GeoLite2 or GeoIP2 City
+// database. Names are reported in English.
+type MaxMindGeolocator struct {
+	db *geoip2.Reader
+}
+
+func NewMaxMindGeolocator(path string) (*MaxMindGeolocator, error) {
+	db, err := geoip2.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &MaxMindGeolocator{db: db}, nil
+}
+
+func (g *MaxMindGeolocator) Locate(ip net.IP) (GeoInfo, error) {
+	record, err := g.db.City(ip)
+	if err != nil {
+		return GeoInfo{}, err
+	}
+
+	info := GeoInfo{
+		Country: record.Country.Names["en"],
+		City:    record.City.Names["en"],
+	}
+	if info.Country == "" {
+		info.Country = record.Country.IsoCode
+	}
+	if len(record.Subdivisions) > 0 {
+		info.Region = record.Subdivisions[0].Names["en"]
+	}
+	return info, nil
+}
+
+// newGeolocator returns the MaxMind backend when dbPath is set and the
+// no-op one otherwise.
+func newGeolocator(dbPath string) (Geolocator, error) {
+	if dbPath == "" {
+		return NoopGeolocator{}, nil
+	}
+	return NewMaxMindGeolocator(dbPath)
+}
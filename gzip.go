@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// gzipMinSize is roughly one Ethernet frame; smaller responses are sent as is.
+const gzipMinSize = 1400
+
+// incompressibleTypes are sent as is: compressing them wastes CPU, and for
+// /download it would defeat the point of a throughput test.
+var incompressibleTypes = []string{"application/octet-stream", "application/gzip", "application/zip", "image/", "video/", "audio/"}
+
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	level      int
+	statusCode int
+	buf        []byte
+	gz         *gzip.Writer
+	started    bool
+}
+
+func (g *gzipResponseWriter) WriteHeader(statusCode int) {
+	if g.statusCode == 0 {
+		g.statusCode = statusCode
+	}
+}
+
+func (g *gzipResponseWriter) Write(b []byte) (int, error) {
+	if g.statusCode == 0 {
+		g.statusCode = http.StatusOK
+	}
+	if g.started {
+		if g.gz != nil {
+			return g.gz.Write(b)
+		}
+		return g.ResponseWriter.Write(b)
+	}
+
+	g.buf = append(g.buf, b...)
+	if len(g.buf) >= gzipMinSize {
+		if err := g.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// start sends the headers and any buffered bytes, compressing them when
+// compress is true, the handler did not set its own Content-Encoding and the
+// content type is worth compressing.
+func (g *gzipResponseWriter) start(compress bool) error {
+	g.started = true
+
+	header := g.Header()
+	if compress && header.Get("Content-Encoding") == "" && !hasAnyPrefix(header.Get("Content-Type"), incompressibleTypes) {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		gz, err := gzip.NewWriterLevel(g.ResponseWriter, g.level)
+		if err != nil {
+			return err
+		}
+		g.gz = gz
+	}
+
+	g.ResponseWriter.WriteHeader(g.statusCode)
+
+	buf := g.buf
+	g.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if g.gz != nil {
+		_, err := g.gz.Write(buf)
+		return err
+	}
+	_, err := g.ResponseWriter.Write(buf)
+	return err
+}
+
+// Flush sends whatever has been buffered so far; a response that is flushed
+// before reaching gzipMinSize is streamed uncompressed.
+func (g *gzipResponseWriter) Flush() {
+	if !g.started {
+		if g.statusCode == 0 {
+			g.statusCode = http.StatusOK
+		}
+		if err := g.start(false); err != nil {
+			return
+		}
+	}
+	if g.gz != nil {
+		g.gz.Flush()
+	}
+	if flusher, ok := g.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (g *gzipResponseWriter) Push(target string, opts *http.PushOptions) error {
+	return push(g.ResponseWriter, target, opts)
+}
+
+// Hijack passes the connection through untouched; nothing has been
+// compressed or sent by the time a handler takes it over.
+func (g *gzipResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return hijack(g.ResponseWriter)
+}
+
+func (g *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return g.ResponseWriter
+}
+
+func (g *gzipResponseWriter) Close() error {
+	if !g.started {
+		if g.statusCode == 0 {
+			return nil
+		}
+		return g.start(false)
+	}
+	if g.gz != nil {
+		return g.gz.Close()
+	}
+	return nil
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		if strings.TrimSpace(strings.SplitN(encoding, ";", 2)[0]) == "gzip" {
+			return true
+		}
+	}
+	return false
+}
+
+func WrapWithGzip(h http.Handler, level int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r) {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		// Close is deliberately not deferred: if the handler panics, the
+		// buffered output is dropped so the recovery middleware can still
+		// send a clean error response.
+		gw := &gzipResponseWriter{ResponseWriter: w, level: level}
+		h.ServeHTTP(gw, r)
+		gw.Close()
+	})
+}
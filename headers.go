@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+)
+
+const redactedHeaderValue = "REDACTED"
+
+type HeadersResponse struct {
+	Headers    http.Header `json:"headers"`
+	RemoteAddr string      `json:"remote_addr"`
+}
+
+func headersHandler(redact []string) http.HandlerFunc {
+	redacted := make(map[string]bool, len(redact))
+	for _, name := range redact {
+		redacted[http.CanonicalHeaderKey(name)] = true
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		headers := make(http.Header, len(r.Header))
+		for name, values := range r.Header {
+			if redacted[http.CanonicalHeaderKey(name)] {
+				masked := make([]string, len(values))
+				for i := range masked {
+					masked[i] = redactedHeaderValue
+				}
+				values = masked
+			}
+			headers[name] = values
+		}
+
+		writeJSON(w, http.StatusOK, HeadersResponse{Headers: headers, RemoteAddr: r.RemoteAddr})
+	}
+}
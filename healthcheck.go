@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const healthCheckTimeout = 3 * time.Second
+
+type HealthCheckFunc func(ctx context.Context) error
+
+type ReadyResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// checkRegistry holds named checks that are run together, such as the
+// dependency checks behind /ready.
+type checkRegistry struct {
+	mu     sync.RWMutex
+	checks map[string]HealthCheckFunc
+}
+
+func newCheckRegistry() *checkRegistry {
+	return &checkRegistry{checks: map[string]HealthCheckFunc{}}
+}
+
+func (c *checkRegistry) register(name string, fn HealthCheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[name] = fn
+}
+
+// run runs every check concurrently and returns "ok" or the error message
+// for each, plus whether all of them passed.
+func (c *checkRegistry) run(ctx context.Context) (map[string]string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]string, len(c.checks))
+		healthy = true
+	)
+	for name, check := range c.checks {
+		name, check := name, check
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := "ok"
+			if err := check(ctx); err != nil {
+				result = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			results[name] = result
+			healthy = healthy && result == "ok"
+		}()
+	}
+	wg.Wait()
+	return results, healthy
+}
+
+var healthChecks = newCheckRegistry()
+
+// RegisterHealthCheck adds a dependency check to /ready, replacing any check
+// already registered under name.
+func RegisterHealthCheck(name string, fn func(ctx context.Context) error) {
+	healthChecks.register(name, fn)
+}
+
+// httpHealthCheck reports an error unless url answers with a status below 400.
+func httpHealthCheck(url string) HealthCheckFunc {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		return nil
+	}
+}
+
+func readyHandler(w http.ResponseWriter, r *http.Request) {
+	checks, healthy := healthChecks.run(r.Context())
+	if !healthy {
+		writeJSON(w, http.StatusServiceUnavailable, ReadyResponse{Status: "not ready", Checks: checks})
+		return
+	}
+	writeJSON(w, http.StatusOK, ReadyResponse{Status: "ready", Checks: checks})
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// hostnameHandler returns the pod name from POD_NAME, falling back to
+// K8S_POD_NAME and then to the hostname outside Kubernetes, as a bare line
+// of text. ?fqdn=true qualifies it with the namespace's cluster DNS suffix,
+// and is answered with a JSON 409 when K8S_NAMESPACE is unset.
+func hostnameHandler(w http.ResponseWriter, r *http.Request) {
+	fqdn := false
+	if v := r.URL.Query().Get("fqdn"); v != "" {
+		var err error
+		if fqdn, err = strconv.ParseBool(v); err != nil {
+			http.Error(w, "fqdn must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	metadata := getKubernetesMetadata()
+	name := os.Getenv("POD_NAME")
+	if name == "" {
+		name = metadata.PodName
+	}
+	if name == "" {
+		name = getHostname()
+	}
+	if fqdn {
+		if metadata.Namespace == "" {
+			writeJSON(w, http.StatusConflict, ErrorResponse{Error: "fqdn needs K8S_NAMESPACE, which is not set"})
+			return
+		}
+		name += "." + metadata.Namespace + ".svc.cluster.local"
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, name)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHostnameHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         map[string]string
+		query       string
+		status      int
+		body        string
+		contentType string // text/plain; charset=utf-8 when empty
+	}{
+		{
+			name:   "POD_NAME",
+			env:    map[string]string{"POD_NAME": "web-0", "K8S_POD_NAME": "other"},
+			status: http.StatusOK,
+			body:   "web-0\n",
+		},
+		{
+			name:   "K8S_POD_NAME fallback",
+			env:    map[string]string{"K8S_POD_NAME": "web-1"},
+			status: http.StatusOK,
+			body:   "web-1\n",
+		},
+		{
+			name:   "hostname fallback",
+			status: http.StatusOK,
+			body:   getHostname() + "\n",
+		},
+		{
+			name:   "fqdn",
+			env:    map[string]string{"POD_NAME": "web-0", "K8S_NAMESPACE": "demo"},
+			query:  "?fqdn=true",
+			status: http.StatusOK,
+			body:   "web-0.demo.svc.cluster.local\n",
+		},
+		{
+			name:        "fqdn without namespace",
+			env:         map[string]string{"POD_NAME": "web-0"},
+			query:       "?fqdn=true",
+			status:      http.StatusConflict,
+			body:        `{"error":"fqdn needs K8S_NAMESPACE, which is not set"}`,
+			contentType: "application/json",
+		},
+		{
+			name:   "invalid fqdn",
+			query:  "?fqdn=maybe",
+			status: http.StatusBadRequest,
+			body:   "fqdn must be true or false\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"POD_NAME", "K8S_POD_NAME", "K8S_NAMESPACE"} {
+				t.Setenv(key, tt.env[key])
+			}
+			w := httptest.NewRecorder()
+			hostnameHandler(w, httptest.NewRequest(http.MethodGet, "/hostname"+tt.query, nil))
+
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			if got := w.Body.String(); got != tt.body {
+				t.Errorf("body = %q, want %q", got, tt.body)
+			}
+			contentType := tt.contentType
+			if contentType == "" {
+				contentType = "text/plain; charset=utf-8"
+			}
+			if got := w.Header().Get("Content-Type"); got != contentType {
+				t.Errorf("Content-Type = %q, want %q", got, contentType)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed templates/info.html
+var defaultInfoTemplate string
+
+//go:embed static
+var staticFiles embed.FS
+
+// infoAssets are referenced by the default HTML template and pushed to HTTP/2
+// clients along with the page.
+var infoAssets = []string{"/static/info.css", "/static/favicon.svg"}
+
+func staticHandler() http.Handler {
+	files, err := fs.Sub(staticFiles, "static")
+	if err != nil {
+		panic(err)
+	}
+	return http.StripPrefix("/static/", http.FileServer(http.FS(files)))
+}
+
+// pushAssets server-pushes assets when the connection supports it (HTTP/2)
+// and silently does nothing on HTTP/1.1.
+func pushAssets(w http.ResponseWriter, assets []string) {
+	pusher, ok := w.(http.Pusher)
+	if !ok {
+		return
+	}
+	for _, asset := range assets {
+		if err := pusher.Push(asset, nil); err != nil {
+			return
+		}
+	}
+}
+
+func loadInfoTemplate(path string) (*template.Template, error) {
+	if path != "" {
+		return template.ParseFiles(path)
+	}
+	return template.New("info.html").Parse(defaultInfoTemplate)
+}
+
+func renderHTML(w http.ResponseWriter, tmpl *template.Template, v interface{}) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, v); err != nil {
+		http.Error(w, "Error rendering HTML", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+}
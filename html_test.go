@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// pushRecorder is a ResponseRecorder that also implements http.Pusher.
+type pushRecorder struct {
+	*httptest.ResponseRecorder
+	pushed []string
+}
+
+func (p *pushRecorder) Push(target string, opts *http.PushOptions) error {
+	p.pushed = append(p.pushed, target)
+	return nil
+}
+
+func TestPushAssets(t *testing.T) {
+	w := &pushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	pushAssets(w, infoAssets)
+	if !slices.Equal(w.pushed, infoAssets) {
+		t.Errorf("pushed %v, want %v", w.pushed, infoAssets)
+	}
+
+	// Without http.Pusher, as on HTTP/1.1, nothing is pushed or written.
+	plain := httptest.NewRecorder()
+	pushAssets(plain, infoAssets)
+	if plain.Body.Len() != 0 || len(plain.Header()) != 0 {
+		t.Errorf("pushAssets wrote to an HTTP/1.1 response: %v %q", plain.Header(), plain.Body)
+	}
+}
+
+// TestPushAssetsHTTP2 requests the HTML /info page from the full server over
+// HTTPS and reads the raw HTTP/2 frames, so that a push dropped anywhere in
+// the middleware chain shows up as a missing PUSH_PROMISE.
+func TestPushAssetsHTTP2(t *testing.T) {
+	dir := t.TempDir()
+	cert := newTestCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "localhost"},
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, nil)
+	key, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	ln.Close()
+	startMain(t, "HTTPS_CERT_FILE="+certFile, "HTTPS_KEY_FILE="+keyFile, "TLS_PORT="+port)
+
+	var conn *tls.Conn
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conn, err = tls.Dial("tcp", "127.0.0.1:"+port, &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"h2"}})
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("HTTPS listener did not start: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	defer conn.Close()
+	if proto := conn.ConnectionState().NegotiatedProtocol; proto != "h2" {
+		t.Fatalf("negotiated protocol %q, want h2", proto)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if _, err := io.WriteString(conn, http2.ClientPreface); err != nil {
+		t.Fatal(err)
+	}
+	framer := http2.NewFramer(conn, conn)
+	decoder := hpack.NewDecoder(4096, nil)
+	framer.ReadMetaHeaders = decoder
+	if err := framer.WriteSettings(); err != nil {
+		t.Fatal(err)
+	}
+	var block bytes.Buffer
+	encoder := hpack.NewEncoder(&block)
+	for _, field := range [][2]string{
+		{":method", "GET"}, {":scheme", "https"}, {":authority", "127.0.0.1:" + port},
+		{":path", "/info"}, {"accept", "text/html"},
+	} {
+		encoder.WriteField(hpack.HeaderField{Name: field[0], Value: field[1]})
+	}
+	if err := framer.WriteHeaders(http2.HeadersFrameParam{
+		StreamID: 1, BlockFragment: block.Bytes(), EndStream: true, EndHeaders: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Pushed streams are only started after their PUSH_PROMISE, so reading
+	// until every known stream has ended sees all of them.
+	var promised []string
+	paths := map[uint32]string{}
+	status := map[uint32]string{1: ""}
+	open := map[uint32]bool{1: true}
+	for len(open) > 0 {
+		frame, err := framer.ReadFrame()
+		if err != nil {
+			t.Fatalf("reading frames: %v (pushed %v)", err, promised)
+		}
+		switch f := frame.(type) {
+		case *http2.SettingsFrame:
+			if !f.IsAck() {
+				framer.WriteSettingsAck()
+			}
+		case *http2.PushPromiseFrame:
+			fields, err := decoder.DecodeFull(f.HeaderBlockFragment())
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, field := range fields {
+				if field.Name == ":path" {
+					promised = append(promised, field.Value)
+					paths[f.PromiseID] = field.Value
+				}
+			}
+			open[f.PromiseID] = true
+		case *http2.MetaHeadersFrame:
+			status[f.StreamID] = f.PseudoValue("status")
+			if f.StreamEnded() {
+				delete(open, f.StreamID)
+			}
+		case *http2.DataFrame:
+			if f.StreamEnded() {
+				delete(open, f.StreamID)
+			}
+		case *http2.RSTStreamFrame:
+			t.Fatalf("stream %d reset: %v", f.StreamID, f.ErrCode)
+		case *http2.GoAwayFrame:
+			t.Fatalf("connection closed: %v", f.ErrCode)
+		}
+	}
+
+	if status[1] != "200" {
+		t.Errorf("GET /info status = %q, want 200", status[1])
+	}
+	if !slices.Equal(promised, infoAssets) {
+		t.Errorf("pushed %v, want %v", promised, infoAssets)
+	}
+	for id, path := range paths {
+		if status[id] != "200" {
+			t.Errorf("pushed %s status = %q, want 200", path, status[id])
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// maxIdempotentBodyBytes bounds the responses kept for replay; larger ones
+// are served normally but not cached.
+const maxIdempotentBodyBytes = 1 << 20
+
+var idempotencyKeyPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// idempotentResponse is a recorded response. It is pending while the first
+// request with its key is still being handled. fingerprint identifies that
+// request, so that the key cannot be reused for a different one.
+type idempotentResponse struct {
+	key         string
+	fingerprint string
+	pending     bool
+	statusCode  int
+	header      http.Header
+	body        []byte
+	expires     time.Time
+}
+
+// IdempotencyCache holds the responses to requests that carried an
+// X-Idempotency-Key, evicting the least recently used entry once it holds
+// size of them.
+type IdempotencyCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+func NewIdempotencyCache(size int, ttl time.Duration) *IdempotencyCache {
+	return &IdempotencyCache{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+// begin returns the recorded response for key, if any. Otherwise it reserves
+// key for the request with the given fingerprint; the caller must then call
+// finish or abandon.
+func (c *IdempotencyCache) begin(key, fingerprint string) (idempotentResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*idempotentResponse)
+		if entry.pending || time.Now().Before(entry.expires) {
+			c.order.MoveToFront(elem)
+			return *entry, true
+		}
+		c.remove(elem)
+	}
+
+	c.entries[key] = c.order.PushFront(&idempotentResponse{key: key, fingerprint: fingerprint, pending: true})
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+	return idempotentResponse{}, false
+}
+
+func (c *IdempotencyCache) finish(key string, statusCode int, header http.Header, body []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*idempotentResponse)
+		elem.Value = &idempotentResponse{
+			key:         key,
+			fingerprint: entry.fingerprint,
+			statusCode:  statusCode,
+			header:      header,
+			body:        body,
+			expires:     time.Now().Add(c.ttl),
+		}
+	}
+}
+
+// abandon releases a reservation whose response is not worth replaying, so
+// that a retry is handled afresh.
+func (c *IdempotencyCache) abandon(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+}
+
+func (c *IdempotencyCache) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*idempotentResponse).key)
+}
+
+// idempotencyRecorder passes the response through while keeping a copy.
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	header     http.Header
+	body       bytes.Buffer
+	overflow   bool
+	hijacked   bool
+}
+
+func (rec *idempotencyRecorder) WriteHeader(statusCode int) {
+	if rec.statusCode == 0 {
+		rec.statusCode = statusCode
+		rec.header = rec.ResponseWriter.Header().Clone()
+	}
+	rec.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rec *idempotencyRecorder) Write(b []byte) (int, error) {
+	if rec.statusCode == 0 {
+		rec.WriteHeader(http.StatusOK)
+	}
+	if rec.body.Len()+len(b) > maxIdempotentBodyBytes {
+		rec.overflow = true
+	} else if !rec.overflow {
+		rec.body.Write(b)
+	}
+	return rec.ResponseWriter.Write(b)
+}
+
+func (rec *idempotencyRecorder) Flush() {
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (rec *idempotencyRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := hijack(rec.ResponseWriter)
+	if err == nil {
+		rec.hijacked = true
+	}
+	return conn, rw, err
+}
+
+func (rec *idempotencyRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// idempotentMethods are the methods whose requests can be replayed; safe
+// methods are answered afresh every time.
+var idempotentMethods = map[string]bool{
+	http.MethodPost:   true,
+	http.MethodPut:    true,
+	http.MethodPatch:  true,
+	http.MethodDelete: true,
+}
+
+// requestFingerprint identifies a request by its method, path and body.
+func requestFingerprint(r *http.Request, body []byte) string {
+	sum := sha256.Sum256(body)
+	return r.Method + " " + r.URL.Path + " " + hex.EncodeToString(sum[:])
+}
+
+// WrapWithIdempotency replays the recorded response to a POST, PUT, PATCH
+// or DELETE whose X-Idempotency-Key has been seen before, marked
+// X-Idempotency-Replay: true. The key is bound to the method, path and body
+// of its first request; reusing it for a different one gets 422, and a
+// repeat that arrives while the first request is still running gets 409.
+// Server errors are not recorded, so that they can be retried. Requests for
+// the patterns in skip, such as streaming routes, are never recorded. It
+// must run inside WrapWithRoutePattern.
+func WrapWithIdempotency(h http.Handler, cache *IdempotencyCache, skip map[string]bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("X-Idempotency-Key")
+		if key == "" || !idempotentMethods[r.Method] || skip[routePatternFromContext(r.Context())] {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if !idempotencyKeyPattern.MatchString(key) {
+			http.Error(w, "X-Idempotency-Key must be a UUID", http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if isBodyTooLarge(err) {
+			writeBodyTooLarge(w)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Error reading request body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		fingerprint := requestFingerprint(r, body)
+
+		if cached, ok := cache.begin(key, fingerprint); ok {
+			if cached.fingerprint != fingerprint {
+				writeJSON(w, http.StatusUnprocessableEntity, ErrorResponse{Error: "idempotency key was already used for a different request"})
+				return
+			}
+			if cached.pending {
+				writeJSON(w, http.StatusConflict, ErrorResponse{Error: "a request with this idempotency key is in progress"})
+				return
+			}
+			for name, values := range cached.header {
+				if _, set := w.Header()[name]; !set {
+					w.Header()[name] = values
+				}
+			}
+			w.Header().Set("X-Idempotency-Replay", "true")
+			w.WriteHeader(cached.statusCode)
+			w.Write(cached.body)
+			return
+		}
+
+		rec := &idempotencyRecorder{ResponseWriter: w}
+		completed := false
+		defer func() {
+			if !completed {
+				cache.abandon(key)
+			}
+		}()
+		h.ServeHTTP(rec, r)
+
+		if rec.statusCode == 0 || rec.statusCode >= 500 || rec.overflow || rec.hijacked {
+			return
+		}
+		cache.finish(key, rec.statusCode, rec.header, rec.body.Bytes())
+		completed = true
+	})
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// ipHandler returns the primary address as a bare line of text for shell
+// scripts, IPv4 by default or IPv6 with ?v=6.
+func ipHandler(w http.ResponseWriter, r *http.Request) {
+	family := "ipv4"
+	switch r.URL.Query().Get("v") {
+	case "", "4":
+	case "6":
+		family = "ipv6"
+	default:
+		http.Error(w, "v must be 4 or 6", http.StatusBadRequest)
+		return
+	}
+
+	ip := primaryIPAddress(getAllInterfaces(), family)
+	if ip == "unknown" {
+		http.Error(w, "no "+family+" address", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, ip)
+}
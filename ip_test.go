@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIPHandler(t *testing.T) {
+	tests := []struct {
+		query  string
+		family string
+	}{
+		{"", "ipv4"},
+		{"?v=4", "ipv4"},
+		{"?v=6", "ipv6"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.family+tt.query, func(t *testing.T) {
+			if primaryIPAddress(getAllInterfaces(), tt.family) == "unknown" {
+				t.Skipf("no non-loopback %s address on this host", tt.family)
+			}
+			w := httptest.NewRecorder()
+			ipHandler(w, httptest.NewRequest(http.MethodGet, "/ip"+tt.query, nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+			}
+			if got := w.Header().Get("Content-Type"); got != "text/plain; charset=utf-8" {
+				t.Errorf("Content-Type = %q", got)
+			}
+			body := w.Body.String()
+			line, ok := strings.CutSuffix(body, "\n")
+			if !ok || strings.Contains(line, "\n") {
+				t.Errorf("body %q is not a single line with a trailing newline", body)
+			}
+			ip := net.ParseIP(line)
+			if ip == nil {
+				t.Fatalf("body %q is not an IP address", line)
+			}
+			if got := ipFamily(ip); got != tt.family {
+				t.Errorf("address %s is %s, want %s", ip, got, tt.family)
+			}
+		})
+	}
+}
+
+func TestIPHandlerInvalidVersion(t *testing.T) {
+	w := httptest.NewRecorder()
+	ipHandler(w, httptest.NewRequest(http.MethodGet, "/ip?v=5", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"regexp"
+)
+
+var jsonpCallbackPattern = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$]{0,63}$`)
+
+// jsonpRecorder captures the wrapped handler's response so it can be wrapped
+// in the callback once complete.
+type jsonpRecorder struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (r *jsonpRecorder) Header() http.Header { return r.header }
+
+func (r *jsonpRecorder) WriteHeader(statusCode int) {
+	if r.statusCode == 0 {
+		r.statusCode = statusCode
+	}
+}
+
+func (r *jsonpRecorder) Write(b []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(b)
+}
+
+// WrapWithJSONP answers requests carrying ?callback=name with the JSON
+// response of h wrapped as name(...);. Error responses are passed through
+// unwrapped.
+func WrapWithJSONP(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callback := r.URL.Query().Get("callback")
+		if callback == "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if !jsonpCallbackPattern.MatchString(callback) {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid callback name"})
+			return
+		}
+
+		r = r.Clone(r.Context())
+		r.Header.Set("Accept", "application/json")
+		r.Header.Del("If-None-Match")
+
+		rec := &jsonpRecorder{header: make(http.Header)}
+		h.ServeHTTP(rec, r)
+		if rec.statusCode == 0 {
+			// Nothing was written, for example because the client went away
+			// during an injected delay.
+			return
+		}
+		rec.header.Del("ETag")
+		for name, values := range rec.header {
+			w.Header()[name] = values
+		}
+		if rec.statusCode != http.StatusOK {
+			w.WriteHeader(rec.statusCode)
+			w.Write(rec.body.Bytes())
+			return
+		}
+
+		payload := append(append([]byte(callback+"("), bytes.TrimSpace(rec.body.Bytes())...), ");"...)
+		w.Header().Set("Content-Type", "application/javascript")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusOK)
+		w.Write(payload)
+	})
+}
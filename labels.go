@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// kvEnvHandler serves the key=value list in the env variable as a JSON
+// object, or with ?key= the single value as plain text. kind names the
+// entries in the 404 for a missing key.
+func kvEnvHandler(env, kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		kv := parseKVList(os.Getenv(env))
+
+		if !r.URL.Query().Has("key") {
+			writeJSON(w, http.StatusOK, kv)
+			return
+		}
+		key := r.URL.Query().Get("key")
+		value, ok := kv[key]
+		if !ok {
+			http.Error(w, kind+" not found: "+key, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, value)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestKVEnvHandler(t *testing.T) {
+	t.Setenv("K8S_POD_LABELS", "app=web,version=v1")
+	handler := kvEnvHandler("K8S_POD_LABELS", "label")
+
+	tests := []struct {
+		query  string
+		status int
+		body   string
+	}{
+		{"", http.StatusOK, `{"app":"web","version":"v1"}`},
+		{"?key=app", http.StatusOK, "web\n"},
+		{"?key=tier", http.StatusNotFound, "label not found: tier\n"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, "/labels"+tt.query, nil))
+		if w.Code != tt.status || w.Body.String() != tt.body {
+			t.Errorf("GET /labels%s = %d %q, want %d %q", tt.query, w.Code, w.Body, tt.status, tt.body)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	maxLoadgenRPS      = 100
+	maxLoadgenDuration = 60 * time.Second
+	loadgenTimeout     = 10 * time.Second
+)
+
+// loadgenToken tags the requests loadgen sends, so that they bypass the
+// per-client rate limit. It is generated per process and never sent
+// anywhere but to this server.
+var loadgenToken = newLoadgenToken()
+
+func newLoadgenToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// isLoadgenRequest reports whether r was sent by this server's loadgen.
+func isLoadgenRequest(r *http.Request) bool {
+	got := r.Header.Get("X-Loadgen-Token")
+	return got != "" && subtle.ConstantTimeCompare([]byte(got), []byte(loadgenToken)) == 1
+}
+
+// LoadgenTarget is a listener of this server that loadgen can reach, with
+// the URL of /info on it and a client that can connect to it.
+type LoadgenTarget struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewLoadgenTarget returns a target for the listener bound to addr. A
+// wildcard address is reached over loopback. TLS certificates are not
+// verified, since they name the service rather than the loopback address.
+func NewLoadgenTarget(addr net.Addr, useTLS bool) *LoadgenTarget {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	url := "http://unix/info"
+	switch addr := addr.(type) {
+	case *net.UnixAddr:
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", addr.Name)
+		}
+	case *net.TCPAddr:
+		ip := addr.IP
+		if ip == nil || ip.IsUnspecified() {
+			ip = net.IPv4(127, 0, 0, 1)
+			if addr.IP != nil && addr.IP.To4() == nil {
+				ip = net.IPv6loopback
+			}
+		}
+		scheme := "http"
+		if useTLS {
+			scheme = "https"
+			transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		}
+		url = scheme + "://" + net.JoinHostPort(ip.String(), strconv.Itoa(addr.Port)) + "/info"
+	}
+	return &LoadgenTarget{URL: url, Client: &http.Client{Timeout: loadgenTimeout, Transport: transport}}
+}
+
+type LoadgenResponse struct {
+	TotalRequests int64   `json:"total_requests"`
+	Successful    int64   `json:"successful"`
+	Failed        int64   `json:"failed"`
+	ActualRPS     float64 `json:"actual_rps"`
+}
+
+// loadgenHandler sends GET /info to this server at the requested rate, so a
+// pod can drive its own autoscaling demo. target is set once the listeners
+// are bound.
+func loadgenHandler(target *atomic.Pointer[LoadgenTarget], authUser, authPassword string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		t := target.Load()
+		if t == nil {
+			writeJSON(w, http.StatusServiceUnavailable, ErrorResponse{Error: "no listener that loadgen can reach"})
+			return
+		}
+
+		rps, err := queryInt(r, "rps", 10, 1, maxLoadgenRPS)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		duration, err := queryInt(r, "duration", 10, 1, int(maxLoadgenDuration.Seconds()))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), time.Duration(duration)*time.Second)
+		defer cancel()
+
+		var (
+			wg                 sync.WaitGroup
+			successful, failed atomic.Int64
+		)
+		limiter := rate.NewLimiter(rate.Limit(rps), 1)
+		start := time.Now()
+		for limiter.Wait(ctx) == nil {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if loadgenRequest(ctx, t, authUser, authPassword) {
+					successful.Add(1)
+				} else {
+					failed.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		elapsed := time.Since(start).Seconds()
+		total := successful.Load() + failed.Load()
+		writeJSON(w, http.StatusOK, LoadgenResponse{
+			TotalRequests: total,
+			Successful:    successful.Load(),
+			Failed:        failed.Load(),
+			ActualRPS:     float64(total) / elapsed,
+		})
+	}
+}
+
+// loadgenRequest reports whether a single request got a 2xx answer. Requests
+// still in flight when the run ends are cancelled and count as failed.
+func loadgenRequest(ctx context.Context, target *LoadgenTarget, authUser, authPassword string) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.URL, nil)
+	if err != nil {
+		return false
+	}
+	req.Header.Set("X-Loadgen-Token", loadgenToken)
+	if authUser != "" {
+		req.SetBasicAuth(authUser, authPassword)
+	}
+
+	resp, err := target.Client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	activeRequests atomic.Int32
+	totalRequests  atomic.Int64
+)
+
+// logLevel is the minimum level logged, adjustable at runtime.
+var logLevel slog.LevelVar
+
+var (
+	logger      = newLogger("json")
+	errorLogger = slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{ReplaceAttr: replaceLogAttr}))
+)
+
+// levelFatal marks the last line logged before the process exits.
+const levelFatal = slog.Level(12)
+
+// ecsVersion is the Elastic Common Schema release the ecs format follows.
+const ecsVersion = "8.11.0"
+
+func newLogger(format string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: &logLevel, ReplaceAttr: replaceLogAttr}
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(os.Stdout, opts))
+	case "ecs":
+		opts.ReplaceAttr = replaceECSAttr
+		return slog.New(slog.NewJSONHandler(os.Stdout, opts)).With("ecs.version", ecsVersion)
+	}
+	return slog.New(slog.NewJSONHandler(os.Stdout, opts))
+}
+
+// parseLogLevel accepts debug, info, warn or error, in any case.
+func parseLogLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(s))
+	return level, err
+}
+
+func replaceLogAttr(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.TimeKey && len(groups) == 0 {
+		return slog.String("timestamp", a.Value.Time().Format(time.RFC3339Nano))
+	}
+	if a.Key == slog.LevelKey && len(groups) == 0 && a.Value.Any() == levelFatal {
+		return slog.String(slog.LevelKey, "FATAL")
+	}
+	return a
+}
+
+// replaceECSAttr renames the built-in fields to their ECS names.
+func replaceECSAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
+	}
+	switch a.Key {
+	case slog.TimeKey:
+		return slog.String("@timestamp", a.Value.Time().UTC().Format(time.RFC3339Nano))
+	case slog.LevelKey:
+		if a.Value.Any() == levelFatal {
+			return slog.String("log.level", "fatal")
+		}
+		return slog.String("log.level", strings.ToLower(a.Value.String()))
+	case slog.MessageKey:
+		return slog.String("message", a.Value.String())
+	}
+	return a
+}
+
+func fatal(msg string, args ...any) {
+	logger.Log(context.Background(), levelFatal, msg, args...)
+	os.Exit(1)
+}
+
+func logServerStarted(addr string, tlsEnabled bool) {
+	logger.LogAttrs(context.Background(), slog.LevelInfo, "server started",
+		slog.String("addr", addr),
+		slog.Bool("tls_enabled", tlsEnabled),
+		slog.String("version", Version),
+		slog.String("go_version", runtime.Version()),
+		slog.Int("pid", os.Getpid()),
+		slog.String("start_time", startTime.Format(time.RFC3339Nano)),
+	)
+}
+
+type ConnKey struct{}
+
+// connContext is installed as http.Server.ConnContext so that handlers can
+// reach the connection a request arrived on.
+func connContext(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, ConnKey{}, c)
+}
+
+// localPortFromContext returns the server-side TCP port of the request's
+// connection, or 0 when it is not a TCP connection.
+func localPortFromContext(ctx context.Context) int {
+	conn, _ := ctx.Value(ConnKey{}).(net.Conn)
+	if conn == nil {
+		return 0
+	}
+	if addr, ok := conn.LocalAddr().(*net.TCPAddr); ok {
+		return addr.Port
+	}
+	return 0
+}
+
+// RequestCountKey holds the request's sequence number in this process's
+// lifetime, as counted by WrapWithLogging.
+type RequestCountKey struct{}
+
+func requestCountFromContext(ctx context.Context) int64 {
+	n, _ := ctx.Value(RequestCountKey{}).(int64)
+	return n
+}
+
+// AccessLogEntry describes one completed request.
+type AccessLogEntry struct {
+	Method       string
+	Path         string
+	Proto        string
+	RemoteAddr   string
+	ClientIP     string
+	LocalPort    int
+	StatusCode   int
+	Duration     time.Duration
+	RequestID    string
+	BytesRead    int64
+	BytesWritten int
+}
+
+// AccessLogger writes access log lines, so that LOG_FORMAT can switch their
+// field names without WrapWithLogging knowing about them.
+type AccessLogger interface {
+	LogRequest(ctx context.Context, entry AccessLogEntry)
+}
+
+func newAccessLogger(format string) AccessLogger {
+	if format == "ecs" {
+		return ecsAccessLogger{}
+	}
+	return slogAccessLogger{}
+}
+
+type slogAccessLogger struct{}
+
+func (slogAccessLogger) LogRequest(ctx context.Context, e AccessLogEntry) {
+	logger.LogAttrs(ctx, slog.LevelInfo, "request",
+		slog.String("method", e.Method),
+		slog.String("path", e.Path),
+		slog.String("proto", e.Proto),
+		slog.String("remote_addr", e.RemoteAddr),
+		slog.String("client_ip", e.ClientIP),
+		slog.Int("local_port", e.LocalPort),
+		slog.Int("status_code", e.StatusCode),
+		slog.Float64("latency_ms", float64(e.Duration.Microseconds())/1000),
+		slog.String("request_id", e.RequestID),
+		slog.Int("bytes_written", e.BytesWritten),
+	)
+}
+
+// ecsAccessLogger uses the flat dotted field names of the Elastic Common
+// Schema, with the request ID standing in for trace.id.
+type ecsAccessLogger struct{}
+
+func (ecsAccessLogger) LogRequest(ctx context.Context, e AccessLogEntry) {
+	logger.LogAttrs(ctx, slog.LevelInfo, "request",
+		slog.String("http.request.method", e.Method),
+		slog.String("http.version", strings.TrimPrefix(e.Proto, "HTTP/")),
+		slog.Int("http.response.status_code", e.StatusCode),
+		slog.Int64("http.request.bytes", e.BytesRead),
+		slog.Int("http.response.bytes", e.BytesWritten),
+		slog.String("url.path", e.Path),
+		slog.String("client.ip", e.ClientIP),
+		slog.Int("server.port", e.LocalPort),
+		slog.Int64("event.duration", e.Duration.Nanoseconds()),
+		slog.String("trace.id", e.RequestID),
+	)
+}
+
+// countingBody counts the request body bytes the handler reads.
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+func WrapWithLogging(h http.Handler, accessLog AccessLogger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		activeRequests.Add(1)
+		defer activeRequests.Add(-1)
+		r = r.WithContext(context.WithValue(r.Context(), RequestCountKey{}, totalRequests.Add(1)))
+
+		start := time.Now()
+		rec := newResponseRecorder(w)
+		body := &countingBody{ReadCloser: r.Body}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = body
+		}
+
+		h.ServeHTTP(rec, r)
+		counters.record(routePatternFromContext(r.Context()), rec.statusCode)
+
+		accessLog.LogRequest(r.Context(), AccessLogEntry{
+			Method:       r.Method,
+			Path:         r.URL.Path,
+			Proto:        r.Proto,
+			RemoteAddr:   r.RemoteAddr,
+			ClientIP:     clientIPFromContext(r.Context()),
+			LocalPort:    localPortFromContext(r.Context()),
+			StatusCode:   rec.statusCode,
+			Duration:     time.Since(start),
+			RequestID:    requestIDFromContext(r.Context()),
+			BytesRead:    body.n,
+			BytesWritten: rec.bytesWritten,
+		})
+	})
+}
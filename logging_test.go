@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+)
+
+// captureStdout runs fn with logger writing to a pipe in place of stdout,
+// and returns what was written.
+func captureStdout(t *testing.T, fn func()) []byte {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, previous := os.Stdout, logger
+	os.Stdout = w
+	logger = newLogger("json")
+	defer func() { os.Stdout, logger = stdout, previous }()
+
+	fn()
+	w.Close()
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestLogServerStarted(t *testing.T) {
+	out := captureStdout(t, func() { logServerStarted(":8080", true) })
+
+	var line map[string]any
+	if err := json.Unmarshal(out, &line); err != nil {
+		t.Fatalf("startup line is not JSON: %v: %s", err, out)
+	}
+	want := map[string]any{"level": "INFO", "msg": "server started", "addr": ":8080", "tls_enabled": true}
+	for key, value := range want {
+		if line[key] != value {
+			t.Errorf("%s = %v, want %v", key, line[key], value)
+		}
+	}
+	for _, key := range []string{"version", "go_version", "pid", "start_time"} {
+		if _, ok := line[key]; !ok {
+			t.Errorf("startup line has no %s field: %s", key, out)
+		}
+	}
+}
+
+func TestActiveRequests(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	const n = 5
+
+	started := make(chan struct{}, n)
+	unblock := make(chan struct{})
+	handler := WrapWithLogging(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-unblock
+	}), slogAccessLogger{})
+	total := totalRequests.Load()
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/info", nil))
+		}()
+	}
+	for i := 0; i < n; i++ {
+		<-started
+	}
+	if got := activeRequests.Load(); got != n {
+		t.Errorf("active_requests during processing = %d, want %d", got, n)
+	}
+
+	close(unblock)
+	wg.Wait()
+	if got := activeRequests.Load(); got != 0 {
+		t.Errorf("active_requests afterwards = %d, want 0", got)
+	}
+	if got := totalRequests.Load() - total; got != n {
+		t.Errorf("total_requests increased by %d, want %d", got, n)
+	}
+}
+
+func TestLogLocalPort(t *testing.T) {
+	server := httptest.NewUnstartedServer(WrapWithLogging(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), slogAccessLogger{}))
+	server.Config.ConnContext = connContext
+	port := server.Listener.Addr().(*net.TCPAddr).Port
+
+	out := captureStdout(t, func() {
+		server.Start()
+		defer server.Close()
+
+		resp, err := http.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	})
+
+	var line map[string]any
+	if err := json.Unmarshal(out, &line); err != nil {
+		t.Fatalf("access log line is not JSON: %v: %s", err, out)
+	}
+	if got, ok := line["local_port"].(float64); !ok || int(got) != port {
+		t.Errorf("local_port = %v, want %d", line["local_port"], port)
+	}
+}
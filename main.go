@@ -1,84 +1,870 @@
 /*
    HTTP server responding to /info endpoint on default port 8080
    To use a different port, pass the env variable PORT to the process
+
+   Kubernetes probes can target /healthz (liveness) and /readyz (readiness).
+   /readyz reports ready only after READY_DELAY_SECONDS (default 0) have
+   elapsed since the process started. /ready additionally runs the registered
+   dependency checks, such as a GET of UPSTREAM_URL, with a 3 second timeout.
+   /startup is meant for a startupProbe: it fails until STARTUP_DELAY_SECONDS
+   (default 0) have passed since the process started and all startup checks
+   succeed, then stays up.
+
+   /version reports the Version, Commit and BuildDate stamped into the binary
+   with -ldflags "-X main.Version=...", with the VERSION and GIT_COMMIT
+   environment variables taking precedence. /info reports the same version
+   and commit as build_version and build_commit, leaving them out when
+   neither was set.
+
+   /openapi.json describes every endpoint, including those that are only
+   registered under some settings, as an OpenAPI 3.0 document; /openapi.yaml
+   serves the same document as YAML.
+
+   IPv6 link-local addresses are omitted from /info unless
+   INCLUDE_LINK_LOCAL=true.
+
+   Prometheus metrics are served on /metrics, on the same port by default
+   or on a dedicated port when METRICS_PORT is set. ENABLE_METRICS_ENDPOINT=true
+   adds /metrics/json with plain JSON request counters and runtime figures.
+
+   When running in Kubernetes, pod metadata injected through the downward API
+   (K8S_POD_NAME, K8S_NAMESPACE, K8S_NODE_NAME, K8S_SERVICE_ACCOUNT) is added
+   to the /info response. /labels and /annotations serve K8S_POD_LABELS and
+   K8S_POD_ANNOTATIONS (key1=val1,key2=val2), or one value with ?key=. When
+   HEADLESS_SERVICE_DNS names a headless service, /peers lists the pods
+   behind it and whether each one accepts connections, cached for
+   PEERS_CACHE_TTL_SECONDS (default 10).
+
+   Every request is logged to stdout as a JSON line, or in a human-readable
+   key=value format when LOG_FORMAT=text. LOG_FORMAT=ecs writes JSON with
+   Elastic Common Schema field names for ingestion into ELK. LOG_LEVEL
+   (debug, info, warn or error; default info) sets the minimum level logged.
+
+   On SIGTERM or SIGINT the server stops accepting connections and waits up
+   to SHUTDOWN_TIMEOUT_SECONDS (default 15) for in-flight requests to finish.
+
+   Setting both HTTPS_CERT_FILE and HTTPS_KEY_FILE serves HTTPS on TLS_PORT
+   (default 8443). Plain HTTP keeps running alongside it only when PORT is
+   set explicitly. MTLS_CA_FILE additionally requires HTTPS clients to present
+   a certificate signed by that CA. LISTEN_ADDRS takes a comma-separated list
+   of host:port addresses to serve plain HTTP on instead of PORT.
+   With HTTPS enabled, /healthz reports "warn" once fewer than CERT_WARN_DAYS
+   (default 30) days remain on the certificate and fails with "critical"
+   below CERT_CRITICAL_DAYS (default 7). ENABLE_CERT_ROTATION=true rereads
+   the certificate and key when they change on disk (or at least every 60
+   seconds), so rotated certificates are served without a restart.
+   UNIX_SOCKET_PATH additionally serves plain HTTP on a Unix domain socket
+   created with UNIX_SOCKET_MODE permissions (octal, default 0660). A stale
+   socket file is removed at startup, and the socket is removed again when
+   the server stops.
+
+   Request bodies larger than MAX_REQUEST_BODY_BYTES (default 1048576) are
+   rejected with HTTP 413.
+
+   /echo reflects the request method, URL, headers and body (up to
+   ECHO_MAX_BODY_BYTES, default 65536) back to the caller, along with the
+   client's country, region and city when MAXMIND_DB_PATH names a GeoIP2 or
+   GeoLite2 City database. /headers returns only the request headers, with
+   those named in REDACT_HEADERS (default "Authorization,Cookie") masked.
+   /bounce redirects to BOUNCE_URL with BOUNCE_STATUS (301, 302, 307 or 308;
+   default 302), or to ?url= when its host is in BOUNCE_ALLOWED_DOMAINS.
+   /reflect mirrors the complete request, including the first 64 KB of the
+   body and any trailers, for checking what a gateway rewrote.
+
+   /info answers in YAML when the client sends Accept: application/x-yaml or
+   Accept: text/yaml, as an auto-refreshing HTML page when the client prefers
+   text/html, and in JSON otherwise. TEMPLATE_PATH replaces the embedded HTML
+   template with a custom html/template file, and RESPONSE_TEMPLATE replaces
+   the JSON encoding with a text/template string rendered from the same
+   fields, such as {"service":"{{.Hostname}}"}; a template that does not
+   parse or run makes JSON requests fail with a 500 carrying the error.
+   Responses carry a weak ETag and If-None-Match is answered with 304 while
+   nothing but the uptime and usage figures have changed. ?callback=name
+   returns the JSON as a JSONP script for legacy cross-origin clients; it is
+   refused with 400 while RESPONSE_TEMPLATE is set. /events streams the same
+   data as Server-Sent Events every EVENT_INTERVAL_SECONDS (default 5), and
+   /ws does the same over a WebSocket that also answers {"command":"ping"},
+   for up to WS_MAX_CONNECTIONS (default 100) clients at once.
+
+   CORS headers are added to every response according to CORS_ALLOWED_ORIGINS
+   (default "*"), CORS_ALLOWED_METHODS and CORS_ALLOWED_HEADERS. Setting
+   CORS_ALLOWED_ORIGINS to an empty string disables them.
+
+   X-Forwarded-For and X-Real-IP are trusted to carry the client address only
+   from peers in TRUSTED_PROXIES (comma-separated CIDRs, default the RFC 1918
+   ranges); /echo and the request log report that address as client_ip.
+
+   Setting both BASIC_AUTH_USER and BASIC_AUTH_PASSWORD requires HTTP basic
+   authentication on every endpoint except the probes.
+
+   Each client IP is limited to RATE_LIMIT_RPS requests per second (default
+   10, must be greater than 0) with bursts of up to RATE_LIMIT_BURST (default
+   20).
+
+   DEBUG_ENDPOINTS=true enables the /debug/* introspection endpoints, including
+   /debug/tls with the served certificate when HTTPS is enabled and the
+   net/http/pprof profiles under /debug/pprof/, which move to their own port
+   when DEBUG_PORT is set. It also adds /simulate/gc, which forces a garbage
+   collection (at most once every 5 seconds) and reports what it freed.
+   ENABLE_ENV_ENDPOINT=true enables /env, which lists environment variables
+   (optionally only those matching an ENV_ALLOWLIST prefix) with secrets
+   redacted. ENABLE_ENV_SET=true adds POST /env/set, which takes
+   {"key":"...","value":"..."} with an X-Chaos-Token header, sets the
+   variable and reloads the configuration from the environment. Only the
+   names in MUTABLE_KEYS (default RESPONSE_DELAY_MS,ERROR_RATE,LOG_LEVEL) can
+   be set, and only the response delay, maximum delay, error rate, rate limit
+   and log level variables may be listed there. A value that does not parse
+   is refused with 400; an accepted one takes effect on the next request.
+
+   RESPONSE_DELAY_MS (default 0) delays every /info response; a single request
+   can ask for /info?delay=N instead, capped at MAX_DELAY_MS (default 5000).
+   ERROR_RATE (0.0-1.0, default 0.0) makes /info fail with an injected HTTP
+   500 at that probability.
+
+   Hostname, interfaces and other static /info fields are collected every
+   INFO_REFRESH_INTERVAL_SECONDS (default 60); counters and usage figures are
+   read on each request. INFO_CACHE_TTL_MS (default 0, disabled) additionally
+   serves the whole of /info from a cached snapshot for that long, with an Age
+   header on cached answers.
+
+   ENABLE_CHAOS_ENDPOINTS=true adds the /simulate/* endpoints that break the
+   process on purpose (for example /simulate/oom?confirm=yes allocates memory
+   until it is OOM-killed, /simulate/exit?code=N&delay=M exits with code N
+   after M seconds, /simulate/sigterm starts a graceful shutdown). They
+   require an X-Chaos-Token header matching CHAOS_TOKEN.
+
+   /simulate/throttle?bps=N&size=M streams M bytes at N bytes per second.
+   /simulate/slow trickles a response for testing proxy timeouts, at most
+   SERVER_MAX_SLOW_CONNECTIONS (default 10) at a time.
+   /simulate/hang?timeout=N holds up to SERVER_MAX_HANG_CONNECTIONS (default
+   5) requests open for N seconds without a real answer, for testing
+   connection pools and circuit breakers. It writes a newline every 10
+   seconds so that proxies keep the connection open; with heartbeat=false it
+   sends nothing at all and answers 204 once the time is up.
+
+   POST, PUT, PATCH and DELETE requests carrying an X-Idempotency-Key UUID
+   are answered once; repeats of the same method, path and body within
+   IDEMPOTENCY_TTL_SECONDS (default 300) get the recorded response with
+   X-Idempotency-Replay: true, and reusing the key for a different request
+   gets 422. Streaming routes are never replayed. Up to
+   IDEMPOTENCY_CACHE_SIZE (default 1000) keys are remembered.
+
+   Responses larger than 1400 bytes are gzip-compressed for clients that
+   accept it, at GZIP_LEVEL (1-9, default 6).
+
+   Handlers that run longer than REQUEST_TIMEOUT_MS (default 30000) are
+   cancelled and answered with HTTP 503.
+
+   /stress/cpu?duration=N&cores=M keeps M cores busy for N seconds (max 60)
+   to exercise CPU-based autoscaling, and /stress/memory?size_mb=N&duration=M
+   holds N MiB (max STRESS_MAX_MEMORY_MB, default 512) for M seconds. At most
+   STRESS_MAX_CONCURRENT (default 1) stress requests run at a time.
+   ENABLE_LOADGEN=true adds /loadgen?rps=N&duration=M, which sends N requests
+   per second (max 100) to this server's own /info for M seconds (max 60).
+   Those requests go to the first plain HTTP listener, else the Unix socket,
+   else the HTTPS port when it does not require client certificates, and
+   carry a per-process token that exempts them from the per-client rate
+   limit. /download?size_mb=N streams N MiB (max DOWNLOAD_MAX_MB, default
+   100) of zeros, or of random bytes with random=true, for throughput tests.
+   /upload reads and discards a body of up to UPLOAD_MAX_MB (default 10) and
+   reports the inbound throughput.
+
+   CONFIG_FILE names a YAML or JSON file with the same keys as /config, plus
+   tls_key_file, basic_auth_password and chaos_token, which /config leaves
+   out; its values override the defaults and are in turn overridden by
+   environment variables, including ones set to 0 or false. A variable that
+   does not parse as its setting's type stops the server from starting.
+   Changes to the response delay, error rate, rate limit and log level in the
+   file are applied without a restart.
+
+   Incoming W3C Trace Context headers are honoured and every request gets a
+   span; spans are exported over OTLP/HTTP when OTEL_EXPORTER_OTLP_ENDPOINT
+   is set.
 */
 package main
 
 import (
+	"context"
+	"crypto/x509"
 	"encoding/json"
+	"math/rand"
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"runtime"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"golang.org/x/sync/errgroup"
 )
 
+var startTime = time.Now()
+
 type ServerInfo struct {
-	Hostname  string `json:"hostname"`
-	OS        string `json:"os"`
-	IPAddress string `json:"ip_address"`
-	Network   string `json:"network"`
+	Hostname           string          `json:"hostname" yaml:"hostname"`
+	OS                 string          `json:"os" yaml:"os"`
+	Interfaces         []InterfaceInfo `json:"interfaces" yaml:"interfaces"`
+	UptimeSeconds      float64         `json:"uptime_seconds" yaml:"uptime_seconds"`
+	ContainerID        string          `json:"container_id,omitempty" yaml:"container_id,omitempty"`
+	Process            ProcessInfo     `json:"process" yaml:"process"`
+	System             SystemInfo      `json:"system" yaml:"system"`
+	ActiveSSEClients   int             `json:"active_sse_clients" yaml:"active_sse_clients"`
+	ActiveRequests     int32           `json:"active_requests" yaml:"active_requests"`
+	TotalRequests      int64           `json:"total_requests" yaml:"total_requests"`
+	MemoryLimitBytes   int64           `json:"memory_limit_bytes,omitempty" yaml:"memory_limit_bytes,omitempty"`
+	MemoryCurrentBytes int64           `json:"memory_current_bytes,omitempty" yaml:"memory_current_bytes,omitempty"`
+	CPULimitMillicores int64           `json:"cpu_limit_millicores,omitempty" yaml:"cpu_limit_millicores,omitempty"`
+	CPUUsageMillicores int64           `json:"cpu_usage_millicores,omitempty" yaml:"cpu_usage_millicores,omitempty"`
+	Disk               *DiskInfo       `json:"disk,omitempty" yaml:"disk,omitempty"`
+	TLS                *TLSInfo        `json:"tls,omitempty" yaml:"tls,omitempty"`
+	RequestID          string          `json:"request_id,omitempty" yaml:"request_id,omitempty"`
+	RequestCount       int64           `json:"request_count,omitempty" yaml:"request_count,omitempty"`
+	KubernetesMetadata `yaml:",inline"`
+	BuildInfo          `yaml:",inline"`
 }
 
-func getServerInfo() ServerInfo {
+type KubernetesMetadata struct {
+	PodName        string `json:"pod_name,omitempty" yaml:"pod_name,omitempty"`
+	Namespace      string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	NodeName       string `json:"node_name,omitempty" yaml:"node_name,omitempty"`
+	ServiceAccount string `json:"service_account,omitempty" yaml:"service_account,omitempty"`
+}
+
+type BuildInfo struct {
+	GoVersion    string `json:"go_version" yaml:"go_version"`
+	GoArch       string `json:"go_arch" yaml:"go_arch"`
+	NumCPU       int    `json:"num_cpu" yaml:"num_cpu"`
+	BuildVersion string `json:"build_version,omitempty" yaml:"build_version,omitempty"`
+	BuildCommit  string `json:"build_commit,omitempty" yaml:"build_commit,omitempty"`
+}
+
+type ProcessInfo struct {
+	PID  int `json:"pid" yaml:"pid"`
+	PPID int `json:"ppid" yaml:"ppid"`
+	UID  int `json:"uid" yaml:"uid"`
+	GID  int `json:"gid" yaml:"gid"`
+}
+
+type InterfaceInfo struct {
+	Name      string `json:"name" yaml:"name"`
+	IPAddress string `json:"ip_address" yaml:"ip_address"`
+	Network   string `json:"network" yaml:"network"`
+	IPFamily  string `json:"ip_family" yaml:"ip_family"`
+}
+
+type StatusResponse struct {
+	Status string `json:"status"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+func getHostname() string {
 	hostname, err := os.Hostname()
 	if err != nil {
-		hostname = "unknown"
+		return "unknown"
 	}
+	return hostname
+}
 
+// getStaticServerInfo collects the fields of ServerInfo that only change
+// when the pod is reconfigured, which serverInfoCache refreshes periodically.
+func getStaticServerInfo() ServerInfo {
 	ops := "unknown"
 	if osEnv := runtime.GOOS; osEnv != "" {
 		ops = osEnv
 	}
 
-	ipAddress, network := getIPAddressAndNetwork()
+	memoryLimit, _ := getCgroupMemory(cgroupRoot)
+	cpuLimit, _ := getCgroupCPUInfo(cgroupRoot)
 
 	return ServerInfo{
-		Hostname:  hostname,
-		OS:        ops,
-		IPAddress: ipAddress,
-		Network:   network,
+		Hostname:    getHostname(),
+		OS:          ops,
+		Interfaces:  getAllInterfaces(),
+		ContainerID: getContainerID(),
+		Process:     getProcessInfo(),
+		System:      getSystemInfo(),
+
+		MemoryLimitBytes:   memoryLimit,
+		CPULimitMillicores: cpuLimit,
+
+		KubernetesMetadata: getKubernetesMetadata(),
+		BuildInfo:          getBuildInfo(),
+	}
+}
+
+// getServerInfo merges the cached static fields with the counters and usage
+// figures, which are read fresh on every call.
+func getServerInfo() ServerInfo {
+	info := serverInfoCache.Get()
+
+	_, memoryCurrent := getCgroupMemory(cgroupRoot)
+	_, cpuUsageUsec := getCgroupCPUInfo(cgroupRoot)
+
+	info.UptimeSeconds = time.Since(startTime).Seconds()
+	info.ActiveSSEClients = int(activeSSEClients.Load())
+	info.ActiveRequests = activeRequests.Load()
+	info.TotalRequests = totalRequests.Load()
+	info.MemoryCurrentBytes = memoryCurrent
+	info.CPUUsageMillicores = cpuUsage.observe(cpuUsageUsec, time.Now())
+	info.Disk = getDiskInfo(config.DiskStatPath)
+	return info
+}
+
+// getBuildInfo leaves out the build version and commit when nothing was
+// injected, rather than reporting the "dev" and "unknown" defaults.
+func getBuildInfo() BuildInfo {
+	info := BuildInfo{
+		GoVersion: runtime.Version(),
+		GoArch:    runtime.GOARCH,
+		NumCPU:    runtime.NumCPU(),
+	}
+	if version, ok := buildVersion(); ok {
+		info.BuildVersion = version
+	}
+	if commit, ok := buildCommit(); ok {
+		info.BuildCommit = commit
+	}
+	return info
+}
+
+func getKubernetesMetadata() KubernetesMetadata {
+	return KubernetesMetadata{
+		PodName:        os.Getenv("K8S_POD_NAME"),
+		Namespace:      os.Getenv("K8S_NAMESPACE"),
+		NodeName:       os.Getenv("K8S_NODE_NAME"),
+		ServiceAccount: os.Getenv("K8S_SERVICE_ACCOUNT"),
+	}
+}
+
+func getProcessInfo() ProcessInfo {
+	// os.Getuid and os.Getgid already return -1 on Windows.
+	return ProcessInfo{
+		PID:  os.Getpid(),
+		PPID: os.Getppid(),
+		UID:  os.Getuid(),
+		GID:  os.Getgid(),
 	}
 }
 
-func getIPAddressAndNetwork() (string, string) {
-	addrs, err := net.InterfaceAddrs()
+func getAllInterfaces() []InterfaceInfo {
+	interfaces := []InterfaceInfo{}
+
+	ifaces, err := net.Interfaces()
 	if err != nil {
-		return "unknown", "unknown"
+		return interfaces
 	}
 
-	for _, addr := range addrs {
-		if ipnet, ok := addr.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
-			if ipnet.IP.To4() != nil {
-				return ipnet.IP.String(), ipnet.Network()
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+
+		for _, addr := range addrs {
+			ipnet, ok := addr.(*net.IPNet)
+			if !ok || ipnet.IP.IsLoopback() {
+				continue
 			}
+
+			family := ipFamily(ipnet.IP)
+			if family == "ipv6" && ipnet.IP.IsLinkLocalUnicast() && !config.IncludeLinkLocal {
+				continue
+			}
+
+			interfaces = append(interfaces, InterfaceInfo{
+				Name:      iface.Name,
+				IPAddress: ipnet.IP.String(),
+				Network:   ipnet.Network(),
+				IPFamily:  family,
+			})
 		}
 	}
 
-	return "unknown", "unknown"
+	return interfaces
+}
+
+// primaryIPAddress returns the first address of the given family, or
+// "unknown" when there is none.
+func primaryIPAddress(interfaces []InterfaceInfo, family string) string {
+	for _, iface := range interfaces {
+		if iface.IPFamily == family {
+			return iface.IPAddress
+		}
+	}
+	return "unknown"
+}
+
+func ipFamily(ip net.IP) string {
+	if ip.To4() != nil {
+		return "ipv4"
+	}
+	return "ipv6"
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	jsonResponse, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, "Error encoding JSON", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(jsonResponse)
 }
 
 func main() {
-	http.HandleFunc("/info", func(w http.ResponseWriter, r *http.Request) {
-		serverInfo := getServerInfo()
-		jsonResponse, err := json.Marshal(serverInfo)
+	var err error
+	config, err = loadConfig()
+	if err != nil {
+		fatal("invalid configuration", "error", err.Error())
+	}
+	logger = newLogger(config.LogFormat)
+	if level, err := parseLogLevel(config.LogLevel); err == nil {
+		logLevel.Set(level)
+	}
+
+	shutdownTracing, err := setupTracing(context.Background(), config.OTLPEndpoint)
+	if err != nil {
+		fatal("failed to set up tracing", "error", err.Error())
+	}
+
+	requestTimeout := time.Duration(config.RequestTimeoutMS) * time.Millisecond
+
+	serverInfoCache = NewServerInfoCache(getStaticServerInfo)
+	go serverInfoCache.RefreshEvery(context.Background(), time.Duration(config.InfoRefreshIntervalSeconds)*time.Second)
+
+	mux := http.NewServeMux()
+	route := func(pattern string, handler http.Handler, timeout time.Duration) {
+		if timeout > 0 {
+			handler = WrapWithTimeout(handler, timeout)
+		}
+		mux.Handle(pattern, otelhttp.NewHandler(handler, pattern))
+	}
+	handle := func(pattern string, handler http.HandlerFunc) {
+		route(pattern, handler, requestTimeout)
+	}
+	// Streaming handlers are not wrapped in the request timeout, which would
+	// buffer their output until they return.
+	streamRoutes := map[string]bool{}
+	handleStream := func(pattern string, handler http.HandlerFunc) {
+		streamRoutes[pattern] = true
+		route(pattern, handler, 0)
+	}
+
+	var delayConfig atomic.Pointer[DelayConfig]
+	delayConfig.Store(newDelayConfig(config))
+
+	infoTemplate, err := loadInfoTemplate(config.TemplatePath)
+	if err != nil {
+		fatal("failed to load info template", "error", err.Error())
+	}
+	responseTemplate := loadResponseTemplate(config.ResponseTemplate)
+	if responseTemplate != nil && responseTemplate.err != nil {
+		logger.Error("failed to parse RESPONSE_TEMPLATE", "error", responseTemplate.err.Error())
+	}
+	infoMediaTypes := []string{"application/json", "application/x-yaml", "text/yaml", "text/html"}
+
+	faults := NewFaultInjector(config.ErrorRate, rand.NewSource(time.Now().UnixNano()))
+	limiter := NewIPRateLimiter(config.RateLimitRPS, config.RateLimitBurst)
+
+	// reloadConfig applies the settings that can change without a restart,
+	// after CONFIG_FILE or a variable set through /env/set changes.
+	reloadConfig := func(c Config) {
+		delayConfig.Store(newDelayConfig(c))
+		faults.SetRate(c.ErrorRate)
+		limiter.SetLimits(c.RateLimitRPS, c.RateLimitBurst)
+		applyReloadedConfig(c)
+	}
+
+	infoCache := NewResponseCache(time.Duration(config.InfoCacheTTLMS) * time.Millisecond)
+	infoHandler := func(w http.ResponseWriter, r *http.Request) {
+		query, err := readInfoQuery(r)
+		if isBodyTooLarge(err) {
+			writeBodyTooLarge(w)
+			return
+		}
 		if err != nil {
-			http.Error(w, "Error encoding JSON", http.StatusInternalServerError)
+			http.Error(w, "invalid JSON body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if query != nil {
+			if unknown := unknownInfoFields(query.Fields); len(unknown) > 0 {
+				writeUnknownFields(w, unknown)
+				return
+			}
+		}
+		if !applyDelay(w, r, *delayConfig.Load()) {
 			return
 		}
+		if faults.ShouldFail() {
+			writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "injected fault"})
+			return
+		}
+		info, age, cached := infoCache.Get(getServerInfo)
+		if cached {
+			w.Header().Set("Age", ageHeader(age))
+		}
+		info.TLS = getTLSInfo(r)
+		info.RequestID = requestIDFromContext(r.Context())
+		info.RequestCount = requestCountFromContext(r.Context())
+		if query != nil && len(query.Fields) > 0 {
+			selected, err := selectInfoFields(info, query.Fields)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, selected)
+			return
+		}
+		mediaType := selectMediaType(r, infoMediaTypes)
+		if mediaType != "" && checkNotModified(w, r, infoETag(info, mediaType)) {
+			return
+		}
+		if mediaType == "text/html" {
+			pushAssets(w, infoAssets)
+			renderHTML(w, infoTemplate, info)
+			return
+		}
+		if mediaType == "application/json" && responseTemplate != nil {
+			// Template output need not be JSON, so it cannot be safely
+			// wrapped in a JSONP callback.
+			if r.URL.Query().Has("callback") {
+				writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "callback is not supported when RESPONSE_TEMPLATE is set"})
+				return
+			}
+			renderResponseTemplate(w, responseTemplate, info)
+			return
+		}
+		negotiate(w, r, info)
+	}
+	handle("/info", WrapWithJSONP(http.HandlerFunc(infoHandler)).ServeHTTP)
 
-		w.Header().Set("Content-Type", "application/json")
-		w.Write(jsonResponse)
+	handle("/static/", staticHandler().ServeHTTP)
+
+	// leafCert returns the certificate being served, or is nil without TLS.
+	var leafCert func() *x509.Certificate
+	var certReloader *CertReloader
+	var certExpiry *CertExpiry
+	if config.TLSEnabled {
+		if config.EnableCertRotation {
+			certReloader, err = NewCertReloader(config.TLSCertFile, config.TLSKeyFile)
+			if err != nil {
+				fatal("failed to load TLS certificate", "path", config.TLSCertFile, "error", err.Error())
+			}
+			leafCert = certReloader.Leaf
+		} else {
+			leaf, err := readLeafCertificate(config.TLSCertFile)
+			if err != nil {
+				fatal("failed to read TLS certificate", "path", config.TLSCertFile, "error", err.Error())
+			}
+			leafCert = func() *x509.Certificate { return leaf }
+		}
+		certExpiry = NewCertExpiry(leafCert)
+		go certExpiry.CheckEvery(context.Background(), certExpiryCheckInterval)
+	}
+	handle("/healthz", healthzHandler(certExpiry, config.CertWarnDays, config.CertCriticalDays))
+
+	readyAt := startTime.Add(time.Duration(config.ReadyDelaySeconds) * time.Second)
+
+	handle("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if time.Now().Before(readyAt) {
+			writeJSON(w, http.StatusServiceUnavailable, StatusResponse{Status: "not ready"})
+			return
+		}
+		writeJSON(w, http.StatusOK, StatusResponse{Status: "ok"})
 	})
 
-	port := "8080"
-	if portEnv := os.Getenv("PORT"); portEnv != "" {
-		port = portEnv
+	if config.UpstreamURL != "" {
+		RegisterHealthCheck("upstream", httpHealthCheck(config.UpstreamURL))
+	}
+	handle("/ready", readyHandler)
+	handle("/startup", startupHandler)
+
+	geolocator, err := newGeolocator(config.MaxMindDBPath)
+	if err != nil {
+		fatal("failed to open MAXMIND_DB_PATH", "path", config.MaxMindDBPath, "error", err.Error())
+	}
+	handle("/echo", echoHandler(int64(config.EchoMaxBodyBytes), geolocator))
+	handle("/reflect", reflectHandler)
+	handle("/headers", headersHandler(config.RedactHeaders))
+	handle("/bounce", bounceHandler(config.BounceURL, config.BounceStatus, config.BounceAllowedDomains))
+
+	handleStream("/download", downloadHandler(config.DownloadMaxMB))
+	handle("/upload", uploadHandler)
+	handleStream("/events", eventsHandler(time.Duration(config.EventIntervalSeconds)*time.Second))
+	handleStream("/ws", websocketHandler(time.Duration(config.EventIntervalSeconds)*time.Second, config.WSMaxConnections))
+
+	handle("/dns", dnsHandler)
+	handle("/tcp", WrapWithRateLimit(http.HandlerFunc(tcpHandler), NewIPRateLimiter(5, 5)).ServeHTTP)
+	handle("/netstat", netstatHandler)
+
+	stress := newStressSlots(config.StressMaxConcurrent)
+	route("/stress/cpu", stressCPUHandler(stress), maxStressDuration+requestTimeout)
+	route("/stress/memory", stressMemoryHandler(stress, config.StressMaxMemoryMB), maxStressDuration+requestTimeout)
+
+	var loadgenTarget atomic.Pointer[LoadgenTarget]
+	if config.EnableLoadgen {
+		route("/loadgen", loadgenHandler(&loadgenTarget, config.BasicAuthUser, config.BasicAuthPassword), maxLoadgenDuration+requestTimeout)
+	}
+
+	if config.HeadlessServiceDNS != "" {
+		discovery := NewPeerDiscovery(config.HeadlessServiceDNS, config.Port, time.Duration(config.PeersCacheTTLSeconds)*time.Second)
+		handle("/peers", peersHandler(discovery))
+	}
+
+	if config.EnableChaosEndpoints {
+		handle("/simulate/oom", WrapWithChaosToken(http.HandlerFunc(simulateOOMHandler), config.ChaosToken).ServeHTTP)
+		handle("/simulate/exit", WrapWithChaosToken(http.HandlerFunc(simulateExitHandler), config.ChaosToken).ServeHTTP)
+		handle("/simulate/sigterm", WrapWithChaosToken(http.HandlerFunc(simulateSIGTERMHandler), config.ChaosToken).ServeHTTP)
+	}
+
+	handleStream("/simulate/throttle", simulateThrottleHandler(config.DownloadMaxMB<<20))
+	handleStream("/simulate/slow", simulateSlowHandler(newStressSlots(config.SlowMaxConnections)))
+	handleStream("/simulate/hang", simulateHangHandler(newStressSlots(config.HangMaxConnections)))
+
+	handle("/version", versionHandler)
+	handle("/config", configHandler)
+	handle("/whoami", whoamiHandler)
+	handle("/labels", kvEnvHandler("K8S_POD_LABELS", "label"))
+	handle("/annotations", kvEnvHandler("K8S_POD_ANNOTATIONS", "annotation"))
+	handle("/ip", ipHandler)
+	handle("/hostname", hostnameHandler)
+	handle("/time", timeHandler)
+	handle("/openapi.json", openAPIJSONHandler)
+	handle("/openapi.yaml", openAPIYAMLHandler)
+
+	if config.DebugEndpoints {
+		handle("/debug/runtime", debugRuntimeHandler)
+		handle("/simulate/gc", simulateGCHandler())
+		if leafCert != nil {
+			handle("/debug/tls", debugTLSHandler(leafCert))
+		}
+	}
+
+	if config.EnableEnvEndpoint {
+		handle("/env", envHandler(config.EnvAllowlist))
+	}
+	if config.EnableEnvSet {
+		handle("/env/set", WrapWithChaosToken(envSetHandler(config.MutableKeys, reloadConfig), config.ChaosToken).ServeHTTP)
+	}
+	if config.EnableMetricsEndpoint {
+		handle("/metrics/json", metricsJSONHandler)
+	}
+
+	shutdownTimeout := config.ShutdownTimeoutSeconds
+
+	listenAddrs := config.ListenAddrs
+	httpEnabled := !config.TLSEnabled || config.PortSet || len(listenAddrs) > 0
+	if len(listenAddrs) == 0 {
+		listenAddrs = []string{":" + config.Port}
+	}
+
+	servers := []*http.Server{}
+	listeners := []func() error{}
+	// Addresses are bound up front so that a port conflict stops the process
+	// before anything reports it as started.
+	addServer := func(server *http.Server, network, certFile, keyFile string) net.Listener {
+		ln, err := net.Listen(network, server.Addr)
+		if err != nil {
+			fatal("failed to listen", "addr", server.Addr, "error", err.Error())
+		}
+		logServerStarted(server.Addr, server.TLSConfig != nil)
+		server.ConnContext = connContext
+
+		servers = append(servers, server)
+		listeners = append(listeners, func() error {
+			if server.TLSConfig != nil {
+				return server.ServeTLS(ln, certFile, keyFile)
+			}
+			return server.Serve(ln)
+		})
+		return ln
+	}
+
+	metricsPort := config.MetricsPort
+	if metricsPort == "" || metricsPort == config.Port {
+		mux.Handle("/metrics", promhttp.Handler())
+	} else {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", promhttp.Handler())
+
+		metricsAddr := ":" + metricsPort
+		addServer(&http.Server{Addr: metricsAddr, Handler: metricsMux}, "tcp", "", "")
+	}
+
+	// The pprof routes are only registered at all when DEBUG_ENDPOINTS is
+	// set. They skip the request timeout, since /debug/pprof/profile and
+	// /debug/pprof/trace run for as long as the client asks.
+	if config.DebugEndpoints {
+		if debugPort := config.DebugPort; debugPort == "" || debugPort == config.Port {
+			for pattern, h := range pprofHandlers {
+				route(pattern, h, 0)
+			}
+		} else {
+			debugMux := http.NewServeMux()
+			for pattern, h := range pprofHandlers {
+				debugMux.Handle(pattern, h)
+			}
+
+			debugAddr := ":" + debugPort
+			addServer(&http.Server{Addr: debugAddr, Handler: debugMux}, "tcp", "", "")
+		}
+	}
+
+	corsConfig := CORSConfig{
+		AllowedOrigins: config.CORSAllowedOrigins,
+		AllowedMethods: config.CORSAllowedMethods,
+		AllowedHeaders: config.CORSAllowedHeaders,
+	}
+
+	idempotencyCache := NewIdempotencyCache(config.IdempotencyCacheSize, time.Duration(config.IdempotencyTTLSeconds)*time.Second)
+	var handler http.Handler = WrapWithIdempotency(instrumentHandler(mux), idempotencyCache, streamRoutes)
+	if config.GzipEnabled {
+		handler = WrapWithGzip(handler, config.GzipLevel)
+	}
+	if config.BasicAuthUser != "" {
+		handler = WrapWithBasicAuth(handler, config.BasicAuthUser, config.BasicAuthPassword)
+	}
+	handler = WrapWithRateLimit(handler, limiter)
+	handler = WrapWithCORS(handler, corsConfig)
+	handler = WrapWithClientCertHeaders(handler)
+	bodyLimits := BodyLimits{
+		Default: int64(config.MaxRequestBodyBytes),
+		Paths:   map[string]int64{"/upload": int64(config.UploadMaxMB) << 20},
+	}
+	handler = WrapWithBodyLimit(handler, bodyLimits, requestTimeout)
+	handler = WrapWithLogging(handler, newAccessLogger(config.LogFormat))
+	// The route pattern is looked up outside logging, so that the JSON
+	// counters, Prometheus and idempotency all see the same one.
+	handler = WrapWithRoutePattern(handler, mux)
+	handler = WrapWithClientIP(handler, config.TrustedProxies)
+	handler = WrapWithRequestID(handler)
+	handler = WrapWithRecovery(handler, config.DebugEndpoints)
+
+	// Loadgen prefers plain HTTP, then the Unix socket, then HTTPS unless
+	// it requires client certificates.
+	if httpEnabled {
+		for _, serverAddr := range listenAddrs {
+			ln := addServer(&http.Server{Addr: serverAddr, Handler: handler}, "tcp", "", "")
+			if loadgenTarget.Load() == nil {
+				loadgenTarget.Store(NewLoadgenTarget(ln.Addr(), false))
+			}
+		}
+	}
+
+	if path := config.UnixSocketPath; path != "" {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			fatal("failed to remove stale unix socket", "path", path, "error", err.Error())
+		}
+		ln := addServer(&http.Server{Addr: path, Handler: handler}, "unix", "", "")
+		if loadgenTarget.Load() == nil {
+			loadgenTarget.Store(NewLoadgenTarget(ln.Addr(), false))
+		}
+		if err := os.Chmod(path, os.FileMode(config.UnixSocketMode)); err != nil {
+			fatal("failed to set unix socket mode", "path", path, "error", err.Error())
+		}
+	}
+
+	if config.TLSEnabled {
+		tlsConfig, err := newTLSConfig(config.MTLSCAFile)
+		if err != nil {
+			fatal("failed to load MTLS_CA_FILE", "error", err.Error())
+		}
+
+		certFile, keyFile := config.TLSCertFile, config.TLSKeyFile
+		if certReloader != nil {
+			// ServeTLS leaves the files to GetCertificate when given none.
+			tlsConfig.GetCertificate = certReloader.GetCertificate
+			certFile, keyFile = "", ""
+		}
+
+		tlsAddr := ":" + config.TLSPort
+		ln := addServer(&http.Server{Addr: tlsAddr, Handler: handler, TLSConfig: tlsConfig}, "tcp", certFile, keyFile)
+		if loadgenTarget.Load() == nil && config.MTLSCAFile == "" {
+			loadgenTarget.Store(NewLoadgenTarget(ln.Addr(), true))
+		}
+	}
+
+	startupAt := startTime.Add(time.Duration(config.StartupDelaySeconds) * time.Second)
+	go waitForStartup(context.Background(), startupAt)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+
+	group, ctx := errgroup.WithContext(context.Background())
+	for _, listen := range listeners {
+		listen := listen
+		group.Go(func() error {
+			if err := listen(); err != nil && err != http.ErrServerClosed {
+				return err
+			}
+			return nil
+		})
 	}
 
-	serverAddr := ":" + port
-	println("Server listening on", serverAddr)
-	err := http.ListenAndServe(serverAddr, nil)
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		go func() {
+			err := watchConfigFile(ctx, path, reloadConfig)
+			if err != nil {
+				logger.Error("config watcher stopped", "path", path, "error", err.Error())
+			}
+		}()
+	}
+
+	if certReloader != nil {
+		go func() {
+			if err := certReloader.Watch(ctx); err != nil {
+				logger.Error("certificate watcher stopped", "path", config.TLSCertFile, "error", err.Error())
+			}
+		}()
+	}
+
+	group.Go(func() error {
+		select {
+		case sig := <-signals:
+			logger.Info("shutdown started", "signal", sig.String(), "timeout_seconds", shutdownTimeout)
+		case <-ctx.Done():
+			logger.Info("shutdown started", "reason", "listener failed", "timeout_seconds", shutdownTimeout)
+		}
+		return shutdown(servers, shutdownTracing, time.Duration(shutdownTimeout)*time.Second)
+	})
+
+	err = group.Wait()
+	// fatal exits without running deferred calls, so the socket is removed
+	// here on both paths.
+	removeUnixSocket(config.UnixSocketPath)
 	if err != nil {
-		panic(err)
+		fatal("server stopped with error", "error", err.Error())
+	}
+	logger.Info("shutdown completed")
+}
+
+// removeUnixSocket deletes the socket file at path, if one was configured.
+func removeUnixSocket(path string) {
+	if path == "" {
+		return
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		logger.Warn("failed to remove unix socket", "path", path, "error", err.Error())
+	}
+}
+
+func shutdown(servers []*http.Server, shutdownTracing func(context.Context) error, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); err != nil {
+			return err
+		}
 	}
+	return shutdownTracing(ctx)
 }
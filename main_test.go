@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestMain runs main instead of the tests when startMain re-executes the
+// test binary as a server.
+func TestMain(m *testing.M) {
+	if os.Getenv("SERVERINFO_TEST_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// startMain runs the server in a child process with env added to the
+// environment, waits until it answers /healthz and returns its base URL. The
+// server is stopped with SIGTERM when the test ends; stop does the same
+// earlier and waits for the process to exit.
+func startMain(t *testing.T, env ...string) (baseURL string, stop func()) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), "SERVERINFO_TEST_MAIN=1", "LISTEN_ADDRS="+addr)
+	cmd.Env = append(cmd.Env, env...)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	stopped := false
+	stop = func() {
+		if stopped {
+			return
+		}
+		stopped = true
+		cmd.Process.Signal(syscall.SIGTERM)
+		cmd.Wait()
+	}
+	t.Cleanup(stop)
+
+	baseURL = "http://" + addr
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := http.Get(baseURL + "/healthz")
+		if err == nil {
+			resp.Body.Close()
+			return baseURL, stop
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server did not start: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestGetServerInfoUptime(t *testing.T) {
+	serverInfoCache = NewServerInfoCache(getStaticServerInfo)
+
+	first := getServerInfo().UptimeSeconds
+	if first < 0 {
+		t.Fatalf("uptime_seconds = %v, want >= 0", first)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if second := getServerInfo().UptimeSeconds; second <= first {
+		t.Fatalf("uptime_seconds did not increase: %v then %v", first, second)
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "serverinfo.sock")
+	// A stale file at the path is replaced by the socket.
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, stop := startMain(t, "UNIX_SOCKET_PATH="+path, "UNIX_SOCKET_MODE=0600")
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://serverinfo/info")
+	if err != nil {
+		t.Fatalf("GET /info over %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	var info ServerInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		t.Fatalf("/info is not valid JSON: %v", err)
+	}
+	if info.Hostname == "" {
+		t.Error("hostname is empty")
+	}
+	if fi, err := os.Stat(path); err != nil {
+		t.Error(err)
+	} else if fi.Mode().Perm() != 0o600 {
+		t.Errorf("socket mode = %v, want 0600", fi.Mode().Perm())
+	}
+
+	stop()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("socket still exists after shutdown: %v", err)
+	}
+}
+
+func TestPprofRoutes(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		t.Run("DEBUG_ENDPOINTS="+strconv.FormatBool(enabled), func(t *testing.T) {
+			baseURL, _ := startMain(t, "DEBUG_ENDPOINTS="+strconv.FormatBool(enabled), "DEBUG_PORT=")
+
+			want := http.StatusNotFound
+			if enabled {
+				want = http.StatusOK
+			}
+			for _, path := range []string{"/debug/pprof/", "/debug/pprof/cmdline", "/debug/pprof/symbol"} {
+				resp, err := http.Get(baseURL + path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp.Body.Close()
+				if resp.StatusCode != want {
+					t.Errorf("GET %s = %d, want %d", path, resp.StatusCode, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net/http"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Total number of HTTP requests by endpoint and status code.",
+	}, []string{"endpoint", "code"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Duration of HTTP requests by endpoint.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"endpoint"})
+
+	injectedErrorRate = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "injected_error_rate",
+		Help: "Probability of /info returning an injected HTTP 500.",
+	})
+)
+
+// instrumentHandler labels requests by the route pattern WrapWithRoutePattern
+// found rather than the raw path, which would let arbitrary URLs create
+// series.
+func instrumentHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := newResponseRecorder(w)
+
+		h.ServeHTTP(rec, r)
+
+		endpoint := routePatternFromContext(r.Context())
+		httpRequestDuration.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+		httpRequestsTotal.WithLabelValues(endpoint, strconv.Itoa(rec.statusCode)).Inc()
+	})
+}
+
+// maxTrackedEndpoints bounds the JSON counters so that scanners probing
+// random paths cannot grow them without limit.
+const maxTrackedEndpoints = 100
+
+// Metrics is the /metrics/json view of the request counters kept by the
+// logging middleware, for environments without Prometheus.
+type Metrics struct {
+	RequestsTotal   map[string]int64            `json:"requests_total"`
+	ErrorsTotal     map[string]map[string]int64 `json:"errors_total"`
+	UptimeSeconds   float64                     `json:"uptime_seconds"`
+	Goroutines      int                         `json:"goroutines"`
+	HeapAllocBytes  uint64                      `json:"heap_alloc_bytes"`
+	LastRequestTime string                      `json:"last_request_time,omitempty"`
+	InfoCacheHits   int64                       `json:"info_cache_hits"`
+	InfoCacheMisses int64                       `json:"info_cache_misses"`
+}
+
+type requestCounters struct {
+	mu          sync.Mutex
+	requests    map[string]int64
+	errors      map[string]map[string]int64
+	lastRequest time.Time
+}
+
+var counters = &requestCounters{
+	requests: make(map[string]int64),
+	errors:   make(map[string]map[string]int64),
+}
+
+func (c *requestCounters) record(endpoint string, statusCode int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.requests[endpoint]; !ok && len(c.requests) >= maxTrackedEndpoints {
+		endpoint = "other"
+	}
+	c.requests[endpoint]++
+	if statusCode >= 400 {
+		if c.errors[endpoint] == nil {
+			c.errors[endpoint] = make(map[string]int64)
+		}
+		c.errors[endpoint][strconv.Itoa(statusCode)]++
+	}
+	c.lastRequest = time.Now()
+}
+
+func (c *requestCounters) snapshot() Metrics {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	metrics := Metrics{
+		RequestsTotal:   make(map[string]int64),
+		ErrorsTotal:     make(map[string]map[string]int64),
+		UptimeSeconds:   time.Since(startTime).Seconds(),
+		Goroutines:      runtime.NumGoroutine(),
+		HeapAllocBytes:  m.HeapAlloc,
+		InfoCacheHits:   infoCacheHits.Load(),
+		InfoCacheMisses: infoCacheMisses.Load(),
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for endpoint, n := range c.requests {
+		metrics.RequestsTotal[endpoint] = n
+	}
+	for endpoint, codes := range c.errors {
+		metrics.ErrorsTotal[endpoint] = make(map[string]int64, len(codes))
+		for code, n := range codes {
+			metrics.ErrorsTotal[endpoint][code] = n
+		}
+	}
+	if !c.lastRequest.IsZero() {
+		metrics.LastRequestTime = c.lastRequest.UTC().Format(time.RFC3339)
+	}
+	return metrics
+}
+
+func metricsJSONHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, counters.snapshot())
+}
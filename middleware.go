@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode   int
+	bytesWritten int
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+}
+
+func (rec *responseRecorder) WriteHeader(statusCode int) {
+	rec.statusCode = statusCode
+	rec.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytesWritten += n
+	return n, err
+}
+
+func (rec *responseRecorder) Flush() {
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// Hijack hands the connection over for protocols such as WebSocket; the
+// request is then recorded as 101 Switching Protocols.
+func (rec *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := hijack(rec.ResponseWriter)
+	if err == nil {
+		rec.statusCode = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+func (rec *responseRecorder) Push(target string, opts *http.PushOptions) error {
+	return push(rec.ResponseWriter, target, opts)
+}
+
+func push(w http.ResponseWriter, target string, opts *http.PushOptions) error {
+	if pusher, ok := w.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+func hijack(w http.ResponseWriter) (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := w.(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+)
+
+type TLSInfo struct {
+	Version           string   `json:"version" yaml:"version"`
+	CipherSuite       string   `json:"cipher_suite" yaml:"cipher_suite"`
+	ClientCertSubject string   `json:"client_cert_subject,omitempty" yaml:"client_cert_subject,omitempty"`
+	ClientCertDNSSANs []string `json:"client_cert_dns_sans,omitempty" yaml:"client_cert_dns_sans,omitempty"`
+}
+
+func newTLSConfig(caFile string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile == "" {
+		return tlsConfig, nil
+	}
+
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no certificates found in " + caFile)
+	}
+
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	tlsConfig.ClientCAs = pool
+	return tlsConfig, nil
+}
+
+// verifiedClientCert returns the client certificate of a verified mTLS
+// connection, or nil.
+func verifiedClientCert(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return r.TLS.VerifiedChains[0][0]
+}
+
+func getTLSInfo(r *http.Request) *TLSInfo {
+	if r.TLS == nil {
+		return nil
+	}
+
+	info := &TLSInfo{
+		Version:     tls.VersionName(r.TLS.Version),
+		CipherSuite: tls.CipherSuiteName(r.TLS.CipherSuite),
+	}
+	if cert := verifiedClientCert(r); cert != nil {
+		info.ClientCertSubject = cert.Subject.String()
+		info.ClientCertDNSSANs = cert.DNSNames
+	}
+	return info
+}
+
+func WrapWithClientCertHeaders(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cert := verifiedClientCert(r); cert != nil {
+			w.Header().Set("X-Client-Cert-Subject", cert.Subject.String())
+			w.Header().Set("X-Client-Cert-DNS-SANs", strings.Join(cert.DNSNames, ","))
+		}
+		h.ServeHTTP(w, r)
+	})
+}
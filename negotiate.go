@@ -0,0 +1,89 @@
+package main
+
+import (
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type mediaRange struct {
+	mediaType string
+	quality   float64
+}
+
+func parseAccept(accept string) []mediaRange {
+	ranges := []mediaRange{}
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+		}
+		if quality > 0 {
+			ranges = append(ranges, mediaRange{mediaType: mediaType, quality: quality})
+		}
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+	return ranges
+}
+
+func matchMediaType(mediaType string, offers []string) string {
+	for _, offer := range offers {
+		if mediaType == offer || mediaType == "*/*" {
+			return offer
+		}
+		if strings.HasSuffix(mediaType, "/*") && strings.HasPrefix(offer, strings.TrimSuffix(mediaType, "*")) {
+			return offer
+		}
+	}
+	return ""
+}
+
+// selectMediaType returns the first offer acceptable to the client, the first
+// offer when no Accept header was sent, or "" when nothing matches.
+func selectMediaType(r *http.Request, offers []string) string {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return offers[0]
+	}
+
+	for _, mr := range parseAccept(accept) {
+		if offer := matchMediaType(mr.mediaType, offers); offer != "" {
+			return offer
+		}
+	}
+	return ""
+}
+
+var negotiableMediaTypes = []string{"application/json", "application/x-yaml", "text/yaml"}
+
+func negotiate(w http.ResponseWriter, r *http.Request, v interface{}) {
+	switch selectMediaType(r, negotiableMediaTypes) {
+	case "application/json":
+		writeJSON(w, http.StatusOK, v)
+	case "application/x-yaml", "text/yaml":
+		yamlResponse, err := yaml.Marshal(v)
+		if err != nil {
+			http.Error(w, "Error encoding YAML", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-yaml")
+		w.Write(yamlResponse)
+	default:
+		http.Error(w, "Not Acceptable", http.StatusNotAcceptable)
+	}
+}
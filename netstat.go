@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+)
+
+var errNetstatNotSupported = errors.New("connection states are only available on Linux")
+
+// tcpStates maps the hex "st" column of /proc/net/tcp to state names, as
+// enumerated in the kernel's include/net/tcp_states.h.
+var tcpStates = map[string]string{
+	"01": "ESTABLISHED",
+	"02": "SYN_SENT",
+	"03": "SYN_RECV",
+	"04": "FIN_WAIT1",
+	"05": "FIN_WAIT2",
+	"06": "TIME_WAIT",
+	"07": "CLOSE",
+	"08": "CLOSE_WAIT",
+	"09": "LAST_ACK",
+	"0A": "LISTEN",
+	"0B": "CLOSING",
+	"0C": "NEW_SYN_RECV",
+}
+
+type NetstatResponse struct {
+	States map[string]int `json:"states"`
+	Total  int            `json:"total"`
+}
+
+// parseNetTCP counts the sockets in a /proc/net/tcp or /proc/net/tcp6 file by
+// state. The first line is a column header; the state is the fourth column.
+func parseNetTCP(path string) (map[string]int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	counts := make(map[string]int)
+	scanner := bufio.NewScanner(f)
+	scanner.Scan()
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		state, ok := tcpStates[strings.ToUpper(fields[3])]
+		if !ok {
+			state = "UNKNOWN"
+		}
+		counts[state]++
+	}
+	return counts, scanner.Err()
+}
+
+func netstatHandler(w http.ResponseWriter, r *http.Request) {
+	states, err := tcpStateCounts()
+	if errors.Is(err, errNetstatNotSupported) {
+		writeJSON(w, http.StatusNotImplemented, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	resp := NetstatResponse{States: states}
+	for _, n := range states {
+		resp.Total += n
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,27 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// tcpStateCounts sums IPv4 and IPv6 sockets. tcp6 is missing on kernels
+// booted with IPv6 disabled, which is not an error.
+func tcpStateCounts() (map[string]int, error) {
+	total := make(map[string]int)
+	for _, path := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		counts, err := parseNetTCP(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for state, n := range counts {
+			total[state] += n
+		}
+	}
+	return total, nil
+}
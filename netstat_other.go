@@ -0,0 +1,7 @@
+//go:build !linux
+
+package main
+
+func tcpStateCounts() (map[string]int, error) {
+	return nil, errNetstatNotSupported
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseNetTCP(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    map[string]int
+	}{
+		{"testdata/proc-net-tcp", map[string]int{"LISTEN": 2, "ESTABLISHED": 2, "TIME_WAIT": 1, "CLOSE_WAIT": 1}},
+		{"testdata/proc-net-tcp6", map[string]int{"LISTEN": 1, "ESTABLISHED": 1}},
+		{"testdata/proc-net-tcp-empty", map[string]int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			got, err := parseNetTCP(tt.fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseNetTCP() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNetTCPMissing(t *testing.T) {
+	if _, err := parseNetTCP(filepath.Join(t.TempDir(), "tcp")); err == nil {
+		t.Error("parseNetTCP() of a missing file returned no error")
+	}
+}
@@ -0,0 +1,698 @@
+package main
+
+import (
+	"encoding"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The types below cover the subset of OpenAPI 3.0 that this server's own
+// description needs.
+
+type OpenAPISpec struct {
+	OpenAPI    string               `json:"openapi" yaml:"openapi"`
+	Info       OpenAPIInfo          `json:"info" yaml:"info"`
+	Paths      map[string]*PathItem `json:"paths" yaml:"paths"`
+	Components OpenAPIComponents    `json:"components" yaml:"components"`
+}
+
+type OpenAPIInfo struct {
+	Title       string `json:"title" yaml:"title"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Version     string `json:"version" yaml:"version"`
+}
+
+type OpenAPIComponents struct {
+	Schemas map[string]*Schema `json:"schemas" yaml:"schemas"`
+}
+
+type PathItem struct {
+	Get  *Operation `json:"get,omitempty" yaml:"get,omitempty"`
+	Post *Operation `json:"post,omitempty" yaml:"post,omitempty"`
+}
+
+type Operation struct {
+	Summary     string               `json:"summary" yaml:"summary"`
+	Description string               `json:"description,omitempty" yaml:"description,omitempty"`
+	Parameters  []Parameter          `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty" yaml:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses" yaml:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name" yaml:"name"`
+	In          string  `json:"in" yaml:"in"`
+	Description string  `json:"description,omitempty" yaml:"description,omitempty"`
+	Required    bool    `json:"required,omitempty" yaml:"required,omitempty"`
+	Schema      *Schema `json:"schema" yaml:"schema"`
+}
+
+type RequestBody struct {
+	Description string               `json:"description,omitempty" yaml:"description,omitempty"`
+	Content     map[string]MediaType `json:"content" yaml:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description" yaml:"description"`
+	Content     map[string]MediaType `json:"content,omitempty" yaml:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema" yaml:"schema"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty" yaml:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty" yaml:"type,omitempty"`
+	Format               string             `json:"format,omitempty" yaml:"format,omitempty"`
+	Enum                 []string           `json:"enum,omitempty" yaml:"enum,omitempty"`
+	Default              any                `json:"default,omitempty" yaml:"default,omitempty"`
+	Minimum              *int               `json:"minimum,omitempty" yaml:"minimum,omitempty"`
+	Maximum              *int               `json:"maximum,omitempty" yaml:"maximum,omitempty"`
+	Items                *Schema            `json:"items,omitempty" yaml:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty" yaml:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty" yaml:"additionalProperties,omitempty"`
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// specBuilder assembles an OpenAPISpec, deriving component schemas from the
+// Go types that handlers pass to writeJSON.
+type specBuilder struct {
+	spec *OpenAPISpec
+}
+
+func newSpecBuilder(title, version string) *specBuilder {
+	return &specBuilder{spec: &OpenAPISpec{
+		OpenAPI:    "3.0.3",
+		Info:       OpenAPIInfo{Title: title, Version: version},
+		Paths:      map[string]*PathItem{},
+		Components: OpenAPIComponents{Schemas: map[string]*Schema{}},
+	}}
+}
+
+func (b *specBuilder) path(pattern string) *PathItem {
+	item, ok := b.spec.Paths[pattern]
+	if !ok {
+		item = &PathItem{}
+		b.spec.Paths[pattern] = item
+	}
+	return item
+}
+
+func (b *specBuilder) get(pattern string, op *Operation) {
+	b.path(pattern).Get = op
+}
+
+func (b *specBuilder) post(pattern string, op *Operation) {
+	b.path(pattern).Post = op
+}
+
+// schema returns the schema for t. Named structs are added to the components
+// once and referenced from then on.
+func (b *specBuilder) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: b.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.objectSchema(t)
+		}
+		if _, ok := b.spec.Components.Schemas[t.Name()]; !ok {
+			// Reserve the name first so that self-referencing types terminate.
+			b.spec.Components.Schemas[t.Name()] = &Schema{}
+			*b.spec.Components.Schemas[t.Name()] = *b.objectSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + t.Name()}
+	}
+	return &Schema{}
+}
+
+func (b *specBuilder) objectSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	b.addFields(s, t)
+	return s
+}
+
+// addFields follows encoding/json: embedded structs without a tag are
+// flattened into their parent and fields tagged "-" are left out.
+func (b *specBuilder) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				b.addFields(s, ft)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		s.Properties[name] = b.schema(field.Type)
+	}
+}
+
+func (b *specBuilder) jsonResponse(description string, v any) *Response {
+	return &Response{
+		Description: description,
+		Content:     map[string]MediaType{"application/json": {Schema: b.schema(reflect.TypeOf(v))}},
+	}
+}
+
+func textResponse(description string) *Response {
+	return &Response{
+		Description: description,
+		Content:     map[string]MediaType{"text/plain": {Schema: &Schema{Type: "string"}}},
+	}
+}
+
+func contentResponse(description, contentType string) *Response {
+	return &Response{
+		Description: description,
+		Content:     map[string]MediaType{contentType: {Schema: &Schema{Type: "string"}}},
+	}
+}
+
+func emptyResponse(description string) *Response {
+	return &Response{Description: description}
+}
+
+func queryParam(name, description string, schema *Schema) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: schema}
+}
+
+func requiredQueryParam(name, description string, schema *Schema) Parameter {
+	p := queryParam(name, description, schema)
+	p.Required = true
+	return p
+}
+
+// intRange is the schema of a parameter read with queryInt. A max of 0
+// leaves the upper bound out, for limits that depend on the configuration.
+func intRange(fallback, min, max int) *Schema {
+	s := &Schema{Type: "integer", Default: fallback, Minimum: &min}
+	if max != 0 {
+		s.Maximum = &max
+	}
+	return s
+}
+
+func stringSchema() *Schema {
+	return &Schema{Type: "string"}
+}
+
+func boolSchema() *Schema {
+	return &Schema{Type: "boolean"}
+}
+
+func enumSchema(values ...string) *Schema {
+	return &Schema{Type: "string", Enum: values}
+}
+
+var chaosTokenParam = Parameter{
+	Name:        "X-Chaos-Token",
+	In:          "header",
+	Description: "Must match CHAOS_TOKEN.",
+	Required:    true,
+	Schema:      &Schema{Type: "string"},
+}
+
+// buildOpenAPISpec describes every endpoint the server can register. Those
+// that depend on the configuration say which setting enables them.
+func buildOpenAPISpec() *OpenAPISpec {
+	b := newSpecBuilder("docker-k8s-demo", Version)
+	b.spec.Info.Description = "A small HTTP server for exploring container and Kubernetes behaviour."
+
+	errorResponse := b.jsonResponse("Error", ErrorResponse{})
+	badRequest := textResponse("Invalid query parameter")
+	chaos := func(op *Operation) *Operation {
+		op.Description = strings.TrimSpace(op.Description + " Registered when ENABLE_CHAOS_ENDPOINTS=true.")
+		op.Parameters = append([]Parameter{chaosTokenParam}, op.Parameters...)
+		op.Responses["403"] = errorResponse
+		return op
+	}
+
+	infoResponses := func() map[string]*Response {
+		return map[string]*Response{
+			"200": b.jsonResponse("Server information, or only the requested fields", ServerInfo{}),
+			"304": emptyResponse("Not modified since the ETag in If-None-Match"),
+			"400": b.jsonResponse("Unknown fields requested", UnknownFieldsResponse{}),
+		}
+	}
+	infoParams := []Parameter{
+		queryParam("delay", "Delay the response by this many milliseconds, capped at MAX_DELAY_MS.", &Schema{Type: "integer", Minimum: ptr(0)}),
+		queryParam("callback", "Wrap the response in a JSONP callback.", stringSchema()),
+	}
+	b.get("/info", &Operation{
+		Summary:     "Describe the server, host and pod",
+		Description: "The response is YAML or HTML if the Accept header asks for it.",
+		Parameters:  infoParams,
+		Responses:   infoResponses(),
+	})
+	b.post("/info", &Operation{
+		Summary:    "Describe the server, limited to the given fields",
+		Parameters: infoParams,
+		RequestBody: &RequestBody{
+			Content: map[string]MediaType{"application/json": {Schema: b.schema(reflect.TypeOf(InfoQuery{}))}},
+		},
+		Responses: infoResponses(),
+	})
+	b.get("/static/{path}", &Operation{
+		Summary: "Serve the embedded static files",
+		Parameters: []Parameter{
+			{Name: "path", In: "path", Required: true, Schema: stringSchema()},
+		},
+		Responses: map[string]*Response{
+			"200": contentResponse("File contents", "application/octet-stream"),
+			"404": textResponse("No such file"),
+		},
+	})
+
+	b.get("/healthz", &Operation{
+		Summary:     "Liveness probe",
+		Description: "Reports warn or critical as the TLS certificate nears expiry.",
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("Alive", HealthzResponse{}),
+			"503": b.jsonResponse("TLS certificate about to expire", HealthzResponse{}),
+		},
+	})
+	b.get("/readyz", &Operation{
+		Summary: "Readiness probe, ready after READY_DELAY_SECONDS",
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("Ready", StatusResponse{}),
+			"503": b.jsonResponse("READY_DELAY_SECONDS has not passed yet", StatusResponse{}),
+		},
+	})
+	b.get("/ready", &Operation{
+		Summary: "Readiness probe that runs the dependency checks",
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("All checks passed", ReadyResponse{}),
+			"503": b.jsonResponse("A check failed", ReadyResponse{}),
+		},
+	})
+	b.get("/startup", &Operation{
+		Summary: "Startup probe",
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("Started", StatusResponse{}),
+			"503": b.jsonResponse("Still starting", StatusResponse{}),
+		},
+	})
+
+	echo := func() *Operation {
+		return &Operation{
+			Summary: "Echo the request",
+			Responses: map[string]*Response{
+				"200": b.jsonResponse("The request as received", EchoResponse{}),
+				"413": errorResponse,
+			},
+		}
+	}
+	b.get("/echo", echo())
+	b.post("/echo", echo())
+	reflectOp := func() *Operation {
+		return &Operation{
+			Summary: "Reflect the request, including trailers",
+			Responses: map[string]*Response{
+				"200": b.jsonResponse("The request as received", ReflectResponse{}),
+			},
+		}
+	}
+	b.get("/reflect", reflectOp())
+	b.post("/reflect", reflectOp())
+	b.get("/headers", &Operation{
+		Summary: "List the request headers",
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("Request headers, with REDACT_HEADERS masked", HeadersResponse{}),
+		},
+	})
+	b.get("/bounce", &Operation{
+		Summary:    "Redirect to BOUNCE_URL",
+		Parameters: []Parameter{queryParam("url", "Redirect here instead, if its host is in BOUNCE_ALLOWED_DOMAINS.", stringSchema())},
+		Responses: map[string]*Response{
+			"302": emptyResponse("Redirect; the status follows BOUNCE_STATUS"),
+			"403": errorResponse,
+		},
+	})
+
+	b.get("/download", &Operation{
+		Summary: "Download generated data",
+		Parameters: []Parameter{
+			queryParam("size_mb", "Size in MiB, up to DOWNLOAD_MAX_MB.", intRange(1, 1, 0)),
+			queryParam("random", "Send random bytes instead of zeros.", boolSchema()),
+		},
+		Responses: map[string]*Response{
+			"200": contentResponse("The generated data", "application/octet-stream"),
+			"400": badRequest,
+		},
+	})
+	b.post("/upload", &Operation{
+		Summary: "Receive and discard a body, reporting the throughput",
+		RequestBody: &RequestBody{
+			Content: map[string]MediaType{
+				"application/octet-stream": {Schema: &Schema{Type: "string", Format: "binary"}},
+				"multipart/form-data":      {Schema: &Schema{Type: "object"}},
+			},
+		},
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("What was received", UploadResponse{}),
+			"400": textResponse("Error reading request body"),
+			"413": errorResponse,
+		},
+	})
+	b.get("/events", &Operation{
+		Summary: "Stream server information as server-sent events",
+		Responses: map[string]*Response{
+			"200": contentResponse("An event every EVENT_INTERVAL_SECONDS", "text/event-stream"),
+		},
+	})
+	b.get("/ws", &Operation{
+		Summary:     "Stream server information over a WebSocket",
+		Description: "Send {\"command\":\"ping\"} to get a pong.",
+		Responses: map[string]*Response{
+			"101": emptyResponse("Switching protocols"),
+			"503": textResponse("Too many WebSocket connections"),
+		},
+	})
+
+	b.get("/dns", &Operation{
+		Summary:    "Resolve a host name, following at most 5 CNAME hops",
+		Parameters: []Parameter{requiredQueryParam("host", "Host name to resolve.", stringSchema())},
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("Resolved addresses", DNSResponse{}),
+			"400": badRequest,
+		},
+	})
+	b.get("/tcp", &Operation{
+		Summary: "Check that a TCP port accepts connections",
+		Parameters: []Parameter{
+			requiredQueryParam("host", "Host to connect to.", stringSchema()),
+			requiredQueryParam("port", "Port to connect to.", &Schema{Type: "integer", Minimum: ptr(1), Maximum: ptr(65535)}),
+		},
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("Probe result", TCPProbeResponse{}),
+			"400": badRequest,
+			"429": textResponse("Rate limited"),
+		},
+	})
+	b.get("/netstat", &Operation{
+		Summary: "Count the host's TCP connections by state",
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("Connection counts", NetstatResponse{}),
+			"501": errorResponse,
+		},
+	})
+
+	maxStressSeconds := int(maxStressDuration.Seconds())
+	b.get("/stress/cpu", &Operation{
+		Summary: "Burn CPU",
+		Parameters: []Parameter{
+			queryParam("duration", "Seconds to run for.", intRange(int(defaultStressDuration.Seconds()), 1, maxStressSeconds)),
+			queryParam("cores", "Cores to keep busy, up to the CPU count.", intRange(1, 1, 0)),
+		},
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("Stress finished", CPUStressResponse{}),
+			"400": badRequest,
+			"429": textResponse("Too many concurrent requests"),
+		},
+	})
+	b.get("/stress/memory", &Operation{
+		Summary: "Allocate and hold memory",
+		Parameters: []Parameter{
+			queryParam("size_mb", "MiB to allocate, up to STRESS_MAX_MEMORY_MB.", intRange(64, 1, 0)),
+			queryParam("duration", "Seconds to hold it for.", intRange(int(defaultStressDuration.Seconds()), 1, maxStressSeconds)),
+		},
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("Stress finished", MemoryStressResponse{}),
+			"400": badRequest,
+			"429": textResponse("Too many concurrent requests"),
+		},
+	})
+	b.get("/loadgen", &Operation{
+		Summary:     "Send load to this server's /info",
+		Description: "Registered when ENABLE_LOADGEN=true.",
+		Parameters: []Parameter{
+			queryParam("rps", "Requests per second.", intRange(10, 1, maxLoadgenRPS)),
+			queryParam("duration", "Seconds to run for.", intRange(10, 1, int(maxLoadgenDuration.Seconds()))),
+		},
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("Load generation results", LoadgenResponse{}),
+			"400": badRequest,
+		},
+	})
+	b.get("/peers", &Operation{
+		Summary:     "List the other pods behind the headless service",
+		Description: "Registered when HEADLESS_SERVICE_DNS is set.",
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("Discovered peers", PeersResponse{}),
+		},
+	})
+
+	b.get("/simulate/oom", chaos(&Operation{
+		Summary:    "Allocate memory until the process is killed",
+		Parameters: []Parameter{requiredQueryParam("confirm", "Must be yes.", enumSchema("yes"))},
+		Responses: map[string]*Response{
+			"400": textResponse("confirm=yes is missing"),
+			"409": b.jsonResponse("An out-of-memory simulation is already in progress", ErrorResponse{}),
+		},
+	}))
+	b.get("/simulate/exit", chaos(&Operation{
+		Summary: "Exit the process",
+		Parameters: []Parameter{
+			queryParam("code", "Exit code.", intRange(1, 0, 255)),
+			queryParam("delay", "Seconds to wait before exiting.", intRange(1, 0, 60)),
+		},
+		Responses: map[string]*Response{
+			"202": b.jsonResponse("Exit scheduled", MessageResponse{}),
+			"400": badRequest,
+		},
+	}))
+	b.get("/simulate/sigterm", chaos(&Operation{
+		Summary: "Send SIGTERM to the process",
+		Responses: map[string]*Response{
+			"202": b.jsonResponse("Signal sent", MessageResponse{}),
+		},
+	}))
+
+	b.get("/simulate/throttle", &Operation{
+		Summary: "Stream a response at a fixed byte rate",
+		Parameters: []Parameter{
+			queryParam("bps", "Bytes per second.", intRange(100<<10, minThrottleBPS, maxThrottleBPS)),
+			queryParam("size", "Bytes to send, up to DOWNLOAD_MAX_MB.", intRange(1<<20, 1, 0)),
+		},
+		Responses: map[string]*Response{
+			"200": contentResponse("The throttled data", "application/octet-stream"),
+			"400": badRequest,
+		},
+	})
+	b.get("/simulate/slow", &Operation{
+		Summary: "Stream a response in slow chunks",
+		Parameters: []Parameter{
+			queryParam("chunk_size", "Bytes per chunk.", intRange(100, 1, 64<<10)),
+			queryParam("chunks", "Number of chunks.", intRange(10, 1, 10000)),
+			queryParam("interval_ms", "Milliseconds between chunks.", intRange(1000, 0, 60000)),
+		},
+		Responses: map[string]*Response{
+			"200": textResponse("The chunks"),
+			"400": badRequest,
+			"429": textResponse("Too many concurrent requests"),
+		},
+	})
+	b.get("/simulate/hang", &Operation{
+		Summary: "Hold the request open without responding",
+		Parameters: []Parameter{
+			queryParam("timeout", "Seconds to hang for.", intRange(30, 1, 300)),
+			queryParam("heartbeat", "Write a newline every 10 seconds to keep proxies from timing out; false sends nothing.", &Schema{Type: "boolean", Default: true}),
+		},
+		Responses: map[string]*Response{
+			"200": textResponse("Heartbeat newlines"),
+			"204": emptyResponse("Timeout elapsed, with heartbeat=false"),
+			"400": badRequest,
+			"503": textResponse("Too many hanging requests"),
+		},
+	})
+	b.get("/version", &Operation{
+		Summary:   "Report the build version",
+		Responses: map[string]*Response{"200": b.jsonResponse("Build information", VersionResponse{})},
+	})
+	b.get("/config", &Operation{
+		Summary:   "Report the effective configuration, with secrets left out",
+		Responses: map[string]*Response{"200": b.jsonResponse("Configuration", Config{})},
+	})
+	b.get("/whoami", &Operation{
+		Summary:   "Describe the pod serving the request",
+		Responses: map[string]*Response{"200": b.jsonResponse("Pod identity, address, labels and annotations", WhoamiResponse{})},
+	})
+	for _, kv := range []struct{ path, kind, env string }{
+		{"/labels", "label", "K8S_POD_LABELS"},
+		{"/annotations", "annotation", "K8S_POD_ANNOTATIONS"},
+	} {
+		b.get(kv.path, &Operation{
+			Summary:    "List the pod's " + kv.kind + "s from " + kv.env,
+			Parameters: []Parameter{queryParam("key", "Return only this "+kv.kind+"'s value, as text.", stringSchema())},
+			Responses: map[string]*Response{
+				"200": b.jsonResponse("The "+kv.kind+"s, or one value as text/plain", map[string]string{}),
+				"404": textResponse("No such " + kv.kind),
+			},
+		})
+	}
+	b.get("/ip", &Operation{
+		Summary:    "Report the pod's IP address",
+		Parameters: []Parameter{queryParam("v", "Address family.", enumSchema("4", "6"))},
+		Responses: map[string]*Response{
+			"200": textResponse("The address"),
+			"400": badRequest,
+			"404": textResponse("No address of that family"),
+		},
+	})
+	b.get("/hostname", &Operation{
+		Summary:    "Report the pod name from POD_NAME or K8S_POD_NAME, or the host name outside Kubernetes",
+		Parameters: []Parameter{queryParam("fqdn", "Append the service domain.", boolSchema())},
+		Responses: map[string]*Response{
+			"200": textResponse("The name"),
+			"409": b.jsonResponse("fqdn=true without K8S_NAMESPACE", ErrorResponse{}),
+		},
+	})
+	b.get("/time", &Operation{
+		Summary:    "Report the server's clock",
+		Parameters: []Parameter{queryParam("format", "Return the time as text in this format.", enumSchema("rfc1123"))},
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("The current time, or text with format=rfc1123", TimeResponse{}),
+			"400": badRequest,
+		},
+	})
+
+	b.get("/debug/runtime", &Operation{
+		Summary:     "Report Go runtime statistics",
+		Description: "Registered when DEBUG_ENDPOINTS=true.",
+		Responses:   map[string]*Response{"200": b.jsonResponse("Runtime statistics", RuntimeStats{})},
+	})
+	b.get("/simulate/gc", &Operation{
+		Summary:     "Force a garbage collection",
+		Description: "Registered when DEBUG_ENDPOINTS=true. Allowed once every 5 seconds.",
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("Heap before and after the collection", GCResponse{}),
+			"429": textResponse("Called again too soon"),
+		},
+	})
+	b.get("/debug/tls", &Operation{
+		Summary:     "Describe the serving certificate",
+		Description: "Registered when DEBUG_ENDPOINTS=true and TLS is enabled.",
+		Responses:   map[string]*Response{"200": b.jsonResponse("Certificate details", TLSCertInfo{})},
+	})
+	pprofPatterns := make([]string, 0, len(pprofHandlers))
+	for pattern := range pprofHandlers {
+		pprofPatterns = append(pprofPatterns, pattern)
+	}
+	sort.Strings(pprofPatterns)
+	for _, pattern := range pprofPatterns {
+		b.get(pattern, &Operation{
+			Summary:     "net/http/pprof",
+			Description: "Registered when DEBUG_ENDPOINTS=true, on DEBUG_PORT if set.",
+			Responses:   map[string]*Response{"200": contentResponse("Profile data", "application/octet-stream")},
+		})
+	}
+	b.get("/env", &Operation{
+		Summary:     "List the allowlisted environment variables",
+		Description: "Registered when ENABLE_ENV_ENDPOINT=true.",
+		Responses:   map[string]*Response{"200": b.jsonResponse("Environment variables", map[string]string{})},
+	})
+	b.post("/env/set", &Operation{
+		Summary:     "Set an environment variable and reload the configuration",
+		Description: "Registered when ENABLE_ENV_SET=true. Only the names in MUTABLE_KEYS can be set.",
+		Parameters:  []Parameter{chaosTokenParam},
+		RequestBody: &RequestBody{
+			Content: map[string]MediaType{"application/json": {Schema: b.schema(reflect.TypeOf(EnvVar{}))}},
+		},
+		Responses: map[string]*Response{
+			"200": b.jsonResponse("The variable was set", EnvVar{}),
+			"400": b.jsonResponse("Invalid body, or the value makes the configuration invalid", ErrorResponse{}),
+			"403": b.jsonResponse("Missing X-Chaos-Token, or the name is not in MUTABLE_KEYS", ErrorResponse{}),
+		},
+	})
+	b.get("/metrics/json", &Operation{
+		Summary:     "Report request counters as JSON",
+		Description: "Registered when ENABLE_METRICS_ENDPOINT=true.",
+		Responses:   map[string]*Response{"200": b.jsonResponse("Request counters", Metrics{})},
+	})
+	b.get("/metrics", &Operation{
+		Summary:     "Prometheus metrics",
+		Description: "Served on METRICS_PORT instead when that is set.",
+		Responses:   map[string]*Response{"200": contentResponse("Metrics in the Prometheus text format", "text/plain")},
+	})
+	b.get("/openapi.json", &Operation{
+		Summary:   "This document",
+		Responses: map[string]*Response{"200": contentResponse("OpenAPI 3.0 document", "application/json")},
+	})
+	b.get("/openapi.yaml", &Operation{
+		Summary:   "This document, as YAML",
+		Responses: map[string]*Response{"200": contentResponse("OpenAPI 3.0 document", "application/yaml")},
+	})
+
+	return b.spec
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+var openAPISpec = sync.OnceValue(buildOpenAPISpec)
+
+func openAPIJSONHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, openAPISpec())
+}
+
+var openAPIYAML = sync.OnceValues(func() ([]byte, error) {
+	return yaml.Marshal(openAPISpec())
+})
+
+func openAPIYAMLHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := openAPIYAML()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(body)
+}
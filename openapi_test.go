@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestOpenAPISpec(t *testing.T) {
+	tests := []struct {
+		path      string
+		handler   http.HandlerFunc
+		unmarshal func([]byte, any) error
+	}{
+		{"/openapi.json", openAPIJSONHandler, json.Unmarshal},
+		{"/openapi.yaml", openAPIYAMLHandler, yaml.Unmarshal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.handler(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+			}
+
+			var spec struct {
+				OpenAPI string                    `json:"openapi" yaml:"openapi"`
+				Paths   map[string]map[string]any `json:"paths" yaml:"paths"`
+			}
+			if err := tt.unmarshal(w.Body.Bytes(), &spec); err != nil {
+				t.Fatalf("spec does not parse: %v", err)
+			}
+			if !strings.HasPrefix(spec.OpenAPI, "3.0.") {
+				t.Errorf("openapi = %q, want 3.0.x", spec.OpenAPI)
+			}
+			for _, path := range []string{"/info", "/healthz", "/readyz"} {
+				if _, ok := spec.Paths[path]["get"]; !ok {
+					t.Errorf("spec has no GET %s", path)
+				}
+			}
+		})
+	}
+}
+
+// TestOpenAPISpecCoversRoutes fails when main registers a route that the
+// hand-written spec does not describe. The patterns are read from the
+// handle, handleStream, route and mux.Handle calls in main.go, plus
+// pprofHandlers.
+func TestOpenAPISpecCoversRoutes(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "main.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var patterns []string
+	for pattern := range pprofHandlers {
+		patterns = append(patterns, pattern)
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+		switch fun := call.Fun.(type) {
+		case *ast.Ident:
+			if fun.Name != "handle" && fun.Name != "handleStream" && fun.Name != "route" {
+				return true
+			}
+		case *ast.SelectorExpr:
+			if x, ok := fun.X.(*ast.Ident); !ok || x.Name != "mux" || fun.Sel.Name != "Handle" {
+				return true
+			}
+		default:
+			return true
+		}
+		switch arg := call.Args[0].(type) {
+		case *ast.BasicLit:
+			pattern, err := strconv.Unquote(arg.Value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			patterns = append(patterns, pattern)
+		case *ast.Ident:
+			// The helpers forwarding their own pattern argument, and the
+			// loop over pprofHandlers.
+			if arg.Name != "pattern" {
+				t.Errorf("%v: route pattern %s is not a string literal", fset.Position(call.Pos()), arg.Name)
+			}
+		default:
+			t.Errorf("%v: route pattern is not a string literal", fset.Position(call.Pos()))
+		}
+		return true
+	})
+	if len(patterns) < 10 {
+		t.Fatalf("found only %d routes in main.go: %v", len(patterns), patterns)
+	}
+
+	spec := buildOpenAPISpec()
+	for _, pattern := range patterns {
+		if _, ok := spec.Paths[pattern]; ok {
+			continue
+		}
+		// A subtree pattern such as /static/ is documented by a path below it.
+		covered := false
+		if strings.HasSuffix(pattern, "/") {
+			for path := range spec.Paths {
+				covered = covered || strings.HasPrefix(path, pattern)
+			}
+		}
+		if !covered {
+			t.Errorf("route %s is missing from the OpenAPI spec", pattern)
+		}
+	}
+}
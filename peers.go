@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const peerDialTimeout = 500 * time.Millisecond
+
+type PeerInfo struct {
+	IPAddress string `json:"ip_address"`
+	Host      string `json:"host,omitempty"`
+	Port      int    `json:"port,omitempty"`
+	Reachable *bool  `json:"reachable,omitempty"`
+}
+
+type PeersResponse struct {
+	Service string     `json:"service"`
+	Peers   []PeerInfo `json:"peers"`
+	Error   string     `json:"error,omitempty"`
+}
+
+// PeerDiscovery resolves the pods behind a headless service and caches the
+// answer for ttl so that frequent polling does not hammer cluster DNS.
+type PeerDiscovery struct {
+	service string
+	port    string
+	ttl     time.Duration
+
+	mu      sync.Mutex
+	cached  PeersResponse
+	fetched time.Time
+}
+
+func NewPeerDiscovery(service, port string, ttl time.Duration) *PeerDiscovery {
+	return &PeerDiscovery{service: service, port: port, ttl: ttl}
+}
+
+func (d *PeerDiscovery) Peers(ctx context.Context) PeersResponse {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.fetched.IsZero() && time.Since(d.fetched) < d.ttl {
+		return d.cached
+	}
+	// The result is shared, so a client hanging up must not cut the lookup short.
+	d.cached = d.lookup(context.WithoutCancel(ctx))
+	d.fetched = time.Now()
+	return d.cached
+}
+
+// lookup prefers SRV records, which carry the peer ports, and falls back to
+// the plain A/AAAA records a headless service always publishes.
+func (d *PeerDiscovery) lookup(ctx context.Context) PeersResponse {
+	ctx, cancel := context.WithTimeout(ctx, dnsLookupTimeout)
+	defer cancel()
+
+	response := PeersResponse{Service: d.service, Peers: []PeerInfo{}}
+	if _, srvs, err := net.DefaultResolver.LookupSRV(ctx, "", "", d.service); err == nil && len(srvs) > 0 {
+		for _, srv := range srvs {
+			addrs, err := net.DefaultResolver.LookupIPAddr(ctx, srv.Target)
+			if err != nil {
+				continue
+			}
+			for _, addr := range addrs {
+				response.Peers = append(response.Peers, PeerInfo{
+					IPAddress: addr.IP.String(),
+					Host:      srv.Target,
+					Port:      int(srv.Port),
+				})
+			}
+		}
+	} else {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, d.service)
+		if err != nil {
+			response.Error = err.Error()
+		}
+		for _, addr := range addrs {
+			response.Peers = append(response.Peers, PeerInfo{IPAddress: addr.IP.String()})
+		}
+	}
+
+	d.checkReachable(ctx, response.Peers)
+	return response
+}
+
+func (d *PeerDiscovery) checkReachable(ctx context.Context, peers []PeerInfo) {
+	var wg sync.WaitGroup
+	for i := range peers {
+		peer := &peers[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			port := d.port
+			if peer.Port != 0 {
+				port = strconv.Itoa(peer.Port)
+			}
+
+			dialer := net.Dialer{Timeout: peerDialTimeout}
+			conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(peer.IPAddress, port))
+			reachable := err == nil
+			if reachable {
+				conn.Close()
+			}
+			peer.Reachable = &reachable
+		}()
+	}
+	wg.Wait()
+}
+
+func peersHandler(discovery *PeerDiscovery) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, discovery.Peers(r.Context()))
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const tcpDialTimeout = 5 * time.Second
+
+type TCPProbeResponse struct {
+	Host      string  `json:"host"`
+	Port      int     `json:"port"`
+	Connected bool    `json:"connected"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+func tcpHandler(w http.ResponseWriter, r *http.Request) {
+	host := r.URL.Query().Get("host")
+	if host == "" || len(host) > maxHostnameLen {
+		http.Error(w, "host must be between 1 and 253 characters", http.StatusBadRequest)
+		return
+	}
+
+	port, err := strconv.Atoi(r.URL.Query().Get("port"))
+	if err != nil || port < 1 || port > 65535 {
+		http.Error(w, "port must be between 1 and 65535", http.StatusBadRequest)
+		return
+	}
+
+	dialer := net.Dialer{Timeout: tcpDialTimeout}
+	start := time.Now()
+	conn, err := dialer.DialContext(r.Context(), "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	response := TCPProbeResponse{
+		Host:      host,
+		Port:      port,
+		Connected: err == nil,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		response.Error = err.Error()
+	} else {
+		conn.Close()
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const rateLimiterIdleTimeout = 5 * time.Minute
+
+type visitor struct {
+	limiter  *rate.Limiter
+	lastSeen atomic.Int64
+}
+
+type IPRateLimiter struct {
+	mu       sync.RWMutex
+	rps      rate.Limit
+	burst    int
+	visitors map[string]*visitor
+}
+
+func NewIPRateLimiter(rps float64, burst int) *IPRateLimiter {
+	l := &IPRateLimiter{
+		rps:      rate.Limit(rps),
+		burst:    burst,
+		visitors: map[string]*visitor{},
+	}
+	go l.evictIdle()
+	return l
+}
+
+func (l *IPRateLimiter) limiter(ip string) *rate.Limiter {
+	l.mu.RLock()
+	v, ok := l.visitors[ip]
+	l.mu.RUnlock()
+
+	if !ok {
+		l.mu.Lock()
+		if v, ok = l.visitors[ip]; !ok {
+			v = &visitor{limiter: rate.NewLimiter(l.rps, l.burst)}
+			l.visitors[ip] = v
+		}
+		l.mu.Unlock()
+	}
+
+	v.lastSeen.Store(time.Now().UnixNano())
+	return v.limiter
+}
+
+func (l *IPRateLimiter) Allow(ip string) bool {
+	return l.limiter(ip).Allow()
+}
+
+func (l *IPRateLimiter) evictIdle() {
+	for range time.Tick(time.Minute) {
+		cutoff := time.Now().Add(-rateLimiterIdleTimeout).UnixNano()
+
+		l.mu.Lock()
+		for ip, v := range l.visitors {
+			if v.lastSeen.Load() < cutoff {
+				delete(l.visitors, ip)
+			}
+		}
+		l.mu.Unlock()
+	}
+}
+
+// SetLimits changes the rate and burst for new and existing clients alike.
+func (l *IPRateLimiter) SetLimits(rps float64, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rps, l.burst = rate.Limit(rps), burst
+	for _, v := range l.visitors {
+		v.limiter.SetLimit(l.rps)
+		v.limiter.SetBurst(l.burst)
+	}
+}
+
+// rateLimitMaxRetryAfter is sent when the limit is zero, so that tokens never
+// come back and no wait would be long enough.
+const rateLimitMaxRetryAfter = "60"
+
+func (l *IPRateLimiter) retryAfter() string {
+	l.mu.RLock()
+	rps := l.rps
+	l.mu.RUnlock()
+	if rps <= 0 {
+		return rateLimitMaxRetryAfter
+	}
+	return strconv.Itoa(int(math.Max(1, math.Ceil(1/float64(rps)))))
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func WrapWithRateLimit(h http.Handler, limiter *IPRateLimiter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isLoadgenRequest(r) && !limiter.Allow(remoteIP(r)) {
+			w.Header().Set("Retry-After", limiter.retryAfter())
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		rps  float64
+		want string
+	}{
+		{10, "1"},
+		{1, "1"},
+		{0.25, "4"},
+		{0, rateLimitMaxRetryAfter},
+	}
+	for _, tt := range tests {
+		l := &IPRateLimiter{}
+		l.SetLimits(tt.rps, 1)
+		if got := l.retryAfter(); got != tt.want {
+			t.Errorf("retryAfter() at %v rps = %q, want %q", tt.rps, got, tt.want)
+		}
+	}
+}
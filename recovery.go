@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+func WrapWithRecovery(h http.Handler, exposeHeader bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			errorLogger.Error("panic recovered",
+				"panic", fmt.Sprint(recovered),
+				"stack", string(debug.Stack()),
+				"method", r.Method,
+				"path", r.URL.Path,
+				"request_id", requestIDFromContext(r.Context()),
+			)
+
+			if exposeHeader {
+				w.Header().Set("X-Panic-Recovered", "true")
+			}
+			writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "internal server error"})
+		}()
+
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecoveryKeepsServing(t *testing.T) {
+	errorLogger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, StatusResponse{Status: "ok"})
+	})
+
+	for _, exposeHeader := range []bool{false, true} {
+		server := httptest.NewServer(WrapWithRecovery(mux, exposeHeader))
+
+		resp, err := http.Get(server.URL + "/panic")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusInternalServerError {
+			t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusInternalServerError)
+		}
+		if got := strings.TrimSpace(string(body)); got != `{"error":"internal server error"}` {
+			t.Errorf("body = %s", got)
+		}
+		if got, want := resp.Header.Get("X-Panic-Recovered") == "true", exposeHeader; got != want {
+			t.Errorf("X-Panic-Recovered set = %v, want %v", got, want)
+		}
+
+		resp, err = http.Get(server.URL + "/ok")
+		if err != nil {
+			t.Fatalf("server stopped serving after a panic: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("status after panic = %d, want %d", resp.StatusCode, http.StatusOK)
+		}
+		server.Close()
+	}
+}
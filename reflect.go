@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"time"
+	"unicode/utf8"
+)
+
+const maxReflectBodyBytes = 64 << 10
+
+type ReflectResponse struct {
+	Method           string              `json:"method"`
+	URL              string              `json:"url"`
+	Proto            string              `json:"proto"`
+	Host             string              `json:"host"`
+	Headers          map[string][]string `json:"headers"`
+	Body             string              `json:"body"`
+	BodyEncoding     string              `json:"body_encoding,omitempty"`
+	BodyTruncated    bool                `json:"body_truncated,omitempty"`
+	ContentLength    int64               `json:"content_length"`
+	TransferEncoding []string            `json:"transfer_encoding,omitempty"`
+	Trailer          map[string][]string `json:"trailer,omitempty"`
+	ReceivedAt       string              `json:"received_at"`
+	ServerHostname   string              `json:"server_hostname"`
+	RequestID        string              `json:"request_id"`
+}
+
+// encodeBody returns body as a string, base64-encoded with encoding
+// "base64" when it is not valid UTF-8.
+func encodeBody(body []byte) (text, encoding string) {
+	if !utf8.Valid(body) {
+		return base64.StdEncoding.EncodeToString(body), "base64"
+	}
+	return string(body), ""
+}
+
+// ctxReader fails reads once ctx is done, so a handler stops consuming a
+// body after its request timeout.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// fullURL reconstructs the URL the client asked for, including the scheme
+// and host that r.URL leaves out on the server side.
+func fullURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
+
+// reflectHandler mirrors the whole request back, for seeing what a gateway
+// changed on the way in. The body past the first 64 KB is read and dropped
+// so that any trailers can be reported.
+func reflectHandler(w http.ResponseWriter, r *http.Request) {
+	receivedAt := time.Now()
+	body := ctxReader{ctx: r.Context(), r: r.Body}
+
+	data, err := io.ReadAll(io.LimitReader(body, maxReflectBodyBytes))
+	var rest int64
+	if err == nil {
+		rest, err = io.Copy(io.Discard, body)
+	}
+	if isBodyTooLarge(err) {
+		writeBodyTooLarge(w)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error reading request body", http.StatusBadRequest)
+		return
+	}
+
+	response := ReflectResponse{
+		Method:           r.Method,
+		URL:              fullURL(r),
+		Proto:            r.Proto,
+		Host:             r.Host,
+		Headers:          r.Header,
+		BodyTruncated:    rest > 0,
+		ContentLength:    r.ContentLength,
+		TransferEncoding: r.TransferEncoding,
+		Trailer:          r.Trailer,
+		ReceivedAt:       receivedAt.UTC().Format(time.RFC3339Nano),
+		ServerHostname:   getHostname(),
+		RequestID:        requestIDFromContext(r.Context()),
+	}
+	response.Body, response.BodyEncoding = encodeBody(data)
+	writeJSON(w, http.StatusOK, response)
+}
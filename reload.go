@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// configReloadDebounce collapses the burst of events an editor or a
+// ConfigMap update produces into a single reload.
+const configReloadDebounce = 250 * time.Millisecond
+
+// configMu guards the fields of config that are updated on reload while
+// /config may be reading them.
+var configMu sync.RWMutex
+
+// applyReloadedConfig copies the settings that can change at runtime into
+// config.
+func applyReloadedConfig(c Config) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	config.ResponseDelayMS = c.ResponseDelayMS
+	config.MaxDelayMS = c.MaxDelayMS
+	config.ErrorRate = c.ErrorRate
+	config.RateLimitRPS = c.RateLimitRPS
+	config.RateLimitBurst = c.RateLimitBurst
+	config.LogLevel = c.LogLevel
+	if level, err := parseLogLevel(c.LogLevel); err == nil {
+		logLevel.Set(level)
+	}
+}
+
+// watchConfigFile reloads the configuration whenever path changes and hands
+// the result to apply, until ctx is cancelled. The directory is watched
+// rather than the file, because editors and Kubernetes ConfigMap updates
+// replace the file instead of writing to it.
+func watchConfigFile(ctx context.Context, path string, apply func(Config)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		return err
+	}
+
+	reload := time.NewTimer(0)
+	<-reload.C
+	for {
+		select {
+		case <-ctx.Done():
+			reload.Stop()
+			return nil
+		case err := <-watcher.Errors:
+			logger.Error("config watcher error", "error", err.Error())
+		case <-watcher.Events:
+			reload.Reset(configReloadDebounce)
+		case <-reload.C:
+			c, err := loadConfig()
+			if err != nil {
+				logger.Error("config reload failed", "path", path, "error", err.Error())
+				continue
+			}
+			apply(c)
+			logger.Info("config reloaded",
+				"path", path,
+				"response_delay_ms", c.ResponseDelayMS,
+				"max_delay_ms", c.MaxDelayMS,
+				"error_rate", c.ErrorRate,
+				"rate_limit_rps", c.RateLimitRPS,
+				"rate_limit_burst", c.RateLimitBurst,
+				"log_level", c.LogLevel,
+			)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"text/template"
+)
+
+// ResponseTemplate is the parsed RESPONSE_TEMPLATE. A template that does not
+// parse is kept with its error, which every request then gets back as a 500,
+// so that a typo shows up in the response rather than as a crash loop.
+type ResponseTemplate struct {
+	tmpl *template.Template
+	err  error
+}
+
+// loadResponseTemplate parses RESPONSE_TEMPLATE, returning nil when it is
+// unset so that /info falls back to plain JSON.
+func loadResponseTemplate(text string) *ResponseTemplate {
+	if text == "" {
+		return nil
+	}
+	tmpl, err := template.New("response").Parse(text)
+	return &ResponseTemplate{tmpl: tmpl, err: err}
+}
+
+// renderResponseTemplate writes v through t in place of its JSON encoding.
+// Nothing is sent until the template has run, so a failure can still be
+// reported as a 500.
+func renderResponseTemplate(w http.ResponseWriter, t *ResponseTemplate, v interface{}) {
+	if t.err != nil {
+		writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "response template: " + t.err.Error()})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, v); err != nil {
+		writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "response template: " + err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRenderResponseTemplate(t *testing.T) {
+	info := ServerInfo{
+		Hostname:           "web-0",
+		KubernetesMetadata: KubernetesMetadata{Namespace: "demo"},
+	}
+	tests := []struct {
+		name     string
+		template string
+		status   int
+		body     string // a prefix of the body for errors, whose detail comes from text/template
+	}{
+		{
+			name:     "custom envelope",
+			template: `{"service":"{{.Hostname}}","version":"1.0"}`,
+			status:   http.StatusOK,
+			body:     `{"service":"web-0","version":"1.0"}`,
+		},
+		{
+			name:     "embedded fields",
+			template: `{{.Hostname}}.{{.Namespace}}`,
+			status:   http.StatusOK,
+			body:     `web-0.demo`,
+		},
+		{
+			name:     "parse error",
+			template: `{{.Hostname`,
+			status:   http.StatusInternalServerError,
+			body:     `{"error":"response template: template: response:1: unclosed action`,
+		},
+		{
+			name:     "execute error",
+			template: `{{.NoSuchField}}`,
+			status:   http.StatusInternalServerError,
+			body:     `{"error":"response template: template: response:1:2: executing \"response\"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			renderResponseTemplate(w, loadResponseTemplate(tt.template), info)
+
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			got := strings.TrimSpace(w.Body.String())
+			if (tt.status == http.StatusOK && got != tt.body) || !strings.HasPrefix(got, tt.body) {
+				t.Errorf("body = %s, want %s", got, tt.body)
+			}
+		})
+	}
+}
+
+func TestLoadResponseTemplateUnset(t *testing.T) {
+	if tmpl := loadResponseTemplate(""); tmpl != nil {
+		t.Errorf("loadResponseTemplate(\"\") = %+v, want nil", tmpl)
+	}
+}
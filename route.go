@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"net/http"
+)
+
+// unknownRoute labels requests that match no registered pattern, so that
+// probes of random paths share one metrics series.
+const unknownRoute = "unknown"
+
+type RoutePatternKey struct{}
+
+func routePatternFromContext(ctx context.Context) string {
+	if pattern, _ := ctx.Value(RoutePatternKey{}).(string); pattern != "" {
+		return pattern
+	}
+	return unknownRoute
+}
+
+// WrapWithRoutePattern stores the mux pattern that r matches, such as
+// "/static/" for any asset, in the request context. The Go 1.21 ServeMux
+// does not expose the pattern it dispatched to, so it is looked up with
+// mux.Handler before h runs.
+func WrapWithRoutePattern(h http.Handler, mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), RoutePatternKey{}, pattern)))
+	})
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const (
+	oomChunkSize = 10 << 20
+	oomPause     = 10 * time.Millisecond
+)
+
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// WrapWithChaosToken only lets requests through when their X-Chaos-Token
+// header matches token, so that destructive endpoints are not triggered by
+// accident.
+func WrapWithChaosToken(h http.Handler, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := r.Header.Get("X-Chaos-Token")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			writeJSON(w, http.StatusForbidden, ErrorResponse{Error: "invalid or missing X-Chaos-Token"})
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// oomBallast keeps every chunk reachable so the garbage collector can never
+// give the memory back.
+var oomBallast [][]byte
+
+// oomStarted is set by the first /simulate/oom request; later ones get a 409
+// rather than a second allocating goroutine.
+var oomStarted atomic.Bool
+
+func simulateOOMHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("confirm") != "yes" {
+		http.Error(w, "confirm=yes is required", http.StatusBadRequest)
+		return
+	}
+
+	if !oomStarted.CompareAndSwap(false, true) {
+		writeJSON(w, http.StatusConflict, ErrorResponse{Error: "an out-of-memory simulation is already in progress"})
+		return
+	}
+
+	logger.Warn("simulating out-of-memory condition", "remote_addr", r.RemoteAddr)
+	go func() {
+		for {
+			chunk := make([]byte, oomChunkSize)
+			for i := 0; i < len(chunk); i += os.Getpagesize() {
+				chunk[i] = 1
+			}
+			oomBallast = append(oomBallast, chunk)
+			time.Sleep(oomPause)
+		}
+	}()
+
+	writeJSON(w, http.StatusAccepted, MessageResponse{Message: "allocating memory until the process is killed"})
+}
+
+// exit is swapped out where the process must survive a simulated exit.
+var exit = os.Exit
+
+func simulateExitHandler(w http.ResponseWriter, r *http.Request) {
+	code, err := queryInt(r, "code", 1, 0, 255)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	delay, err := queryInt(r, "delay", 1, 0, 60)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Warn("simulating process exit", "exit_code", code, "delay_seconds", delay, "remote_addr", r.RemoteAddr)
+	// The delay gives the response time to reach the client.
+	time.AfterFunc(time.Duration(delay)*time.Second, func() { exit(code) })
+
+	writeJSON(w, http.StatusAccepted, MessageResponse{
+		Message: "exiting with code " + strconv.Itoa(code) + " in " + strconv.Itoa(delay) + "s",
+	})
+}
+
+// simulateSIGTERMHandler signals the process itself, so shutdown runs
+// through the same path as a Kubernetes pod termination.
+func simulateSIGTERMHandler(w http.ResponseWriter, r *http.Request) {
+	logger.Warn("simulating SIGTERM", "remote_addr", r.RemoteAddr)
+	writeJSON(w, http.StatusAccepted, MessageResponse{Message: "SIGTERM sent, beginning graceful shutdown"})
+	http.NewResponseController(w).Flush()
+
+	// os.Process.Signal rather than syscall.Kill keeps this building on
+	// Windows, where the signal is simply reported as unsupported.
+	process, err := os.FindProcess(os.Getpid())
+	if err == nil {
+		err = process.Signal(syscall.SIGTERM)
+	}
+	if err != nil {
+		logger.Error("failed to send SIGTERM", "error", err.Error())
+	}
+}
+
+// simulateSlowHandler trickles chunks*chunk_size dots to the client, one
+// flushed chunk every interval_ms, for exercising proxy read timeouts.
+func simulateSlowHandler(slots stressSlots) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chunkSize, err := queryInt(r, "chunk_size", 100, 1, 64<<10)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		chunks, err := queryInt(r, "chunks", 10, 1, 10000)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		intervalMS, err := queryInt(r, "interval_ms", 1000, 0, 60000)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		release, ok := slots.acquire()
+		if !ok {
+			http.Error(w, "Too many concurrent slow responses", http.StatusTooManyRequests)
+			return
+		}
+		defer release()
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		rc := http.NewResponseController(w)
+		chunk := bytes.Repeat([]byte("."), chunkSize)
+		ticker := time.NewTicker(time.Duration(max(intervalMS, 1)) * time.Millisecond)
+		defer ticker.Stop()
+
+		for i := 0; i < chunks; i++ {
+			if i > 0 {
+				select {
+				case <-ticker.C:
+				case <-r.Context().Done():
+					return
+				}
+			}
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+const (
+	minThrottleBPS = 100
+	maxThrottleBPS = 10 << 20
+	// throttleWritesPerSecond sets the chunk size: bps/10 bytes per write,
+	// capped at downloadChunkSize.
+	throttleWritesPerSecond = 10
+)
+
+// simulateThrottleHandler sends size zero bytes at bps bytes per second,
+// sleeping chunk/bps between fixed-size writes. Each sleep is measured from
+// the start of the response, so time spent writing does not add up to a
+// slower rate.
+func simulateThrottleHandler(maxSize int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bps, err := queryInt(r, "bps", 100<<10, minThrottleBPS, maxThrottleBPS)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		size, err := queryInt(r, "size", min(1<<20, maxSize), 1, maxSize)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		chunkSize := min(bps/throttleWritesPerSecond, downloadChunkSize)
+		perChunk := time.Duration(chunkSize) * time.Second / time.Duration(bps)
+		chunk := make([]byte, chunkSize)
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", strconv.Itoa(size))
+		rc := http.NewResponseController(w)
+
+		start := time.Now()
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+		<-timer.C
+		for sent, i := 0, 0; sent < size; i++ {
+			if wait := time.Until(start.Add(time.Duration(i) * perChunk)); wait > 0 {
+				timer.Reset(wait)
+				select {
+				case <-timer.C:
+				case <-r.Context().Done():
+					return
+				}
+			}
+			n := min(chunkSize, size-sent)
+			if _, err := w.Write(chunk[:n]); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+			sent += n
+		}
+	}
+}
+
+const hangHeartbeatInterval = 10 * time.Second
+
+// simulateHangHandler holds the request open until the client gives up or
+// ?timeout= seconds pass. It starts a chunked response right away and writes
+// a newline every 10 seconds, so that idle timeouts in proxies along the way
+// do not cut the connection. With ?heartbeat=false it sends nothing until
+// the timeout and then replies 204.
+func simulateHangHandler(slots stressSlots) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		timeout, err := queryInt(r, "timeout", 30, 1, 300)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		heartbeat := true
+		if value := r.URL.Query().Get("heartbeat"); value != "" {
+			if heartbeat, err = strconv.ParseBool(value); err != nil {
+				http.Error(w, "heartbeat must be true or false", http.StatusBadRequest)
+				return
+			}
+		}
+
+		release, ok := slots.acquire()
+		if !ok {
+			http.Error(w, "Too many hanging requests", http.StatusServiceUnavailable)
+			return
+		}
+		defer release()
+
+		deadline := time.NewTimer(time.Duration(timeout) * time.Second)
+		defer deadline.Stop()
+		rc := http.NewResponseController(w)
+
+		var beats <-chan time.Time
+		if heartbeat {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusOK)
+			rc.Flush()
+			ticker := time.NewTicker(hangHeartbeatInterval)
+			defer ticker.Stop()
+			beats = ticker.C
+		}
+
+		for {
+			select {
+			case <-beats:
+				if _, err := w.Write([]byte("\n")); err != nil {
+					return
+				}
+				if err := rc.Flush(); err != nil {
+					return
+				}
+			case <-deadline.C:
+				if !heartbeat {
+					w.WriteHeader(http.StatusNoContent)
+				}
+				return
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSimulateExit(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	codes := make(chan int, 1)
+	exit = func(code int) { codes <- code }
+	defer func() { exit = os.Exit }()
+
+	handler := WrapWithChaosToken(http.HandlerFunc(simulateExitHandler), "t0ken")
+	tests := []struct {
+		name     string
+		query    string
+		token    string
+		status   int
+		body     string
+		exitCode int // -1 when exit must not be called
+	}{
+		{"default code", "?delay=0", "t0ken", http.StatusAccepted, `{"message":"exiting with code 1 in 0s"}`, 1},
+		{"explicit code", "?code=0&delay=0", "t0ken", http.StatusAccepted, `{"message":"exiting with code 0 in 0s"}`, 0},
+		{"code 255", "?code=255&delay=0", "t0ken", http.StatusAccepted, `{"message":"exiting with code 255 in 0s"}`, 255},
+		{"code out of range", "?code=256&delay=0", "t0ken", http.StatusBadRequest, "code must be an integer between 0 and 255", -1},
+		{"missing token", "?delay=0", "", http.StatusForbidden, `{"error":"invalid or missing X-Chaos-Token"}`, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/simulate/exit"+tt.query, nil)
+			if tt.token != "" {
+				r.Header.Set("X-Chaos-Token", tt.token)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			if got := strings.TrimSpace(w.Body.String()); got != tt.body {
+				t.Errorf("body = %s, want %s", got, tt.body)
+			}
+
+			select {
+			case code := <-codes:
+				if code != tt.exitCode {
+					t.Errorf("exit(%d), want exit(%d)", code, tt.exitCode)
+				}
+			case <-time.After(200 * time.Millisecond):
+				if tt.exitCode >= 0 {
+					t.Errorf("exit was not called, want exit(%d)", tt.exitCode)
+				}
+			}
+		})
+	}
+}
+
+func TestSimulateOOMAlreadyStarted(t *testing.T) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	// Pretend an earlier request started the simulation so that this one
+	// must not allocate anything.
+	oomStarted.Store(true)
+	defer oomStarted.Store(false)
+
+	w := httptest.NewRecorder()
+	simulateOOMHandler(w, httptest.NewRequest(http.MethodGet, "/simulate/oom?confirm=yes", nil))
+	if w.Code != http.StatusConflict {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusConflict)
+	}
+	if got := strings.TrimSpace(w.Body.String()); got != `{"error":"an out-of-memory simulation is already in progress"}` {
+		t.Errorf("body = %s", got)
+	}
+	if len(oomBallast) != 0 {
+		t.Errorf("allocated %d chunks after a 409", len(oomBallast))
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const startupRetryInterval = time.Second
+
+var (
+	startupChecks   = newCheckRegistry()
+	startupComplete atomic.Bool
+)
+
+// RegisterStartupCheck adds a check that must pass before /startup reports
+// success. Checks have to be registered before waitForStartup runs.
+func RegisterStartupCheck(name string, fn func(ctx context.Context) error) {
+	startupChecks.register(name, fn)
+}
+
+// waitForStartup marks startup complete once the clock has reached at and
+// every startup check has succeeded in the same round, retrying failed
+// rounds. Unlike readiness, completion is permanent.
+func waitForStartup(ctx context.Context, at time.Time) {
+	if sleepContext(ctx, time.Until(at)) != nil {
+		return
+	}
+	for {
+		results, ok := startupChecks.run(ctx)
+		if ok {
+			startupComplete.Store(true)
+			logger.Info("startup completed", "checks", len(results))
+			return
+		}
+		if sleepContext(ctx, startupRetryInterval) != nil {
+			return
+		}
+	}
+}
+
+func startupHandler(w http.ResponseWriter, r *http.Request) {
+	if !startupComplete.Load() {
+		writeJSON(w, http.StatusServiceUnavailable, StatusResponse{Status: "starting"})
+		return
+	}
+	writeJSON(w, http.StatusOK, StatusResponse{Status: "ok"})
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWaitForStartup(t *testing.T) {
+	t.Cleanup(func() { startupComplete.Store(false) })
+
+	// The delay counts from process start, so a deadline already passed while
+	// the listeners were set up does not hold startup back again.
+	startupComplete.Store(false)
+	done := make(chan struct{})
+	go func() {
+		waitForStartup(context.Background(), time.Now().Add(-time.Second))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("waitForStartup still waiting after its deadline had passed")
+	}
+	if !startupComplete.Load() {
+		t.Error("startup not complete after a past deadline")
+	}
+
+	startupComplete.Store(false)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	waitForStartup(ctx, time.Now().Add(time.Hour))
+	if startupComplete.Load() {
+		t.Error("startup complete before its deadline")
+	}
+}
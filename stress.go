@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	maxStressDuration     = 60 * time.Second
+	defaultStressDuration = 10 * time.Second
+)
+
+// stressSlots bounds how many stress requests run at once across all
+// /stress endpoints.
+type stressSlots chan struct{}
+
+func newStressSlots(maxConcurrent int) stressSlots {
+	return make(stressSlots, maxConcurrent)
+}
+
+// acquire reserves a slot without blocking and returns a function releasing
+// it, or false when every slot is taken.
+func (s stressSlots) acquire() (func(), bool) {
+	select {
+	case s <- struct{}{}:
+		return func() { <-s }, true
+	default:
+		return nil, false
+	}
+}
+
+type CPUStressResponse struct {
+	ActualDurationMS int64 `json:"actual_duration_ms"`
+	GoroutinesUsed   int   `json:"goroutines_used"`
+}
+
+// queryInt returns the named query parameter, fallback when it is absent, or
+// an error when it is not an integer within [min, max].
+func queryInt(r *http.Request, name string, fallback, min, max int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("%s must be an integer between %d and %d", name, min, max)
+	}
+	return n, nil
+}
+
+func burnCPU(ctx context.Context) {
+	x := 0.0
+	for i := 0; ; i++ {
+		x += math.Sqrt(float64(i)) * math.Sin(float64(i))
+		if i%10000 == 0 && ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+func stressCPUHandler(slots stressSlots) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		duration, err := queryInt(r, "duration", int(defaultStressDuration.Seconds()), 1, int(maxStressDuration.Seconds()))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		cores, err := queryInt(r, "cores", 1, 1, runtime.NumCPU())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		release, ok := slots.acquire()
+		if !ok {
+			http.Error(w, "Too many concurrent stress requests", http.StatusTooManyRequests)
+			return
+		}
+		defer release()
+
+		ctx, cancel := context.WithTimeout(r.Context(), time.Duration(duration)*time.Second)
+		defer cancel()
+
+		start := time.Now()
+		var wg sync.WaitGroup
+		for i := 0; i < cores; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				burnCPU(ctx)
+			}()
+		}
+		wg.Wait()
+
+		writeJSON(w, http.StatusOK, CPUStressResponse{
+			ActualDurationMS: time.Since(start).Milliseconds(),
+			GoroutinesUsed:   cores,
+		})
+	}
+}
+
+type MemoryStressResponse struct {
+	AllocatedBytes   int64 `json:"allocated_bytes"`
+	ActualDurationMS int64 `json:"actual_duration_ms"`
+}
+
+func stressMemoryHandler(slots stressSlots, maxMB int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sizeMB, err := queryInt(r, "size_mb", min(64, maxMB), 1, maxMB)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		duration, err := queryInt(r, "duration", int(defaultStressDuration.Seconds()), 1, int(maxStressDuration.Seconds()))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		release, ok := slots.acquire()
+		if !ok {
+			http.Error(w, "Too many concurrent stress requests", http.StatusTooManyRequests)
+			return
+		}
+		defer release()
+
+		ctx := r.Context()
+		start := time.Now()
+		// Allocate a megabyte at a time so that a cancelled request stops
+		// allocating instead of first taking all of size_mb, and touch every
+		// page so the memory is actually resident rather than just reserved.
+		chunks := make([][]byte, 0, sizeMB)
+		for len(chunks) < sizeMB && ctx.Err() == nil {
+			chunk := make([]byte, 1<<20)
+			for i := 0; i < len(chunk); i += os.Getpagesize() {
+				chunk[i] = 1
+			}
+			chunks = append(chunks, chunk)
+		}
+
+		if ctx.Err() == nil {
+			timer := time.NewTimer(time.Duration(duration) * time.Second)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+			}
+		}
+		allocated := int64(len(chunks)) << 20
+		runtime.KeepAlive(chunks)
+		chunks = nil
+		debug.FreeOSMemory()
+
+		writeJSON(w, http.StatusOK, MemoryStressResponse{
+			AllocatedBytes:   allocated,
+			ActualDurationMS: time.Since(start).Milliseconds(),
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestStressCPUStopsOnCancel(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/stress/cpu?duration=30&cores=%d", runtime.NumCPU()), nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	start := time.Now()
+	stressCPUHandler(newStressSlots(1))(w, r)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("handler ran for %v after the request was cancelled", elapsed)
+	}
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+
+	// The burn goroutines have returned once the count is back to where it
+	// started.
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines still running, want %d", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStressCPUTooManyRequests(t *testing.T) {
+	slots := newStressSlots(1)
+	release, _ := slots.acquire()
+	defer release()
+
+	w := httptest.NewRecorder()
+	stressCPUHandler(slots)(w, httptest.NewRequest(http.MethodGet, "/stress/cpu?duration=1", nil))
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+}
+
+func TestStressMemoryCancelledBeforeAllocating(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r := httptest.NewRequest(http.MethodGet, "/stress/memory?size_mb=256&duration=30", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	start := time.Now()
+	stressMemoryHandler(newStressSlots(1), 256)(w, r)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("handler ran for %v after the request was cancelled", elapsed)
+	}
+	var resp MemoryStressResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response %q: %v", w.Body, err)
+	}
+	if resp.AllocatedBytes != 0 {
+		t.Errorf("allocated_bytes = %d, want 0 for a cancelled request", resp.AllocatedBytes)
+	}
+}
+
+func TestStressMemory(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/stress/memory?size_mb=4&duration=1", nil)
+	w := httptest.NewRecorder()
+	stressMemoryHandler(newStressSlots(1), 16)(w, r)
+
+	var resp MemoryStressResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response %q: %v", w.Body, err)
+	}
+	if resp.AllocatedBytes != 4<<20 {
+		t.Errorf("allocated_bytes = %d, want %d", resp.AllocatedBytes, 4<<20)
+	}
+}
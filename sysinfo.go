@@ -0,0 +1,24 @@
+package main
+
+import (
+	"os"
+	"runtime"
+)
+
+// SystemInfo describes the platform the process runs on, for spotting
+// differences between amd64 and arm64 nodes.
+type SystemInfo struct {
+	Arch     string `json:"arch" yaml:"arch"`
+	OSArch   string `json:"os_arch" yaml:"os_arch"`
+	PageSize int    `json:"page_size" yaml:"page_size"`
+	CPUCount int    `json:"cpu_count" yaml:"cpu_count"`
+}
+
+func getSystemInfo() SystemInfo {
+	return SystemInfo{
+		Arch:     runtime.GOARCH,
+		OSArch:   machineArch(),
+		PageSize: os.Getpagesize(),
+		CPUCount: runtime.NumCPU(),
+	}
+}
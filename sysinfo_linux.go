@@ -0,0 +1,20 @@
+//go:build linux
+
+package main
+
+import (
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+// machineArch returns the kernel's machine name, as `uname -m` prints it.
+// It can differ from GOARCH, for example for an amd64 binary emulated on an
+// arm64 node.
+func machineArch() string {
+	var uts unix.Utsname
+	if err := unix.Uname(&uts); err != nil {
+		return runtime.GOARCH
+	}
+	return unix.ByteSliceToString(uts.Machine[:])
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package main
+
+import "runtime"
+
+func machineArch() string {
+	return runtime.GOARCH
+}
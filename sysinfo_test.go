@@ -0,0 +1,23 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestGetSystemInfo(t *testing.T) {
+	info := getSystemInfo()
+
+	if info.Arch != runtime.GOARCH {
+		t.Errorf("arch = %q, want %q", info.Arch, runtime.GOARCH)
+	}
+	if info.OSArch == "" {
+		t.Error("os_arch is empty")
+	}
+	if info.PageSize <= 0 {
+		t.Errorf("page_size = %d, want > 0", info.PageSize)
+	}
+	if info.CPUCount <= 0 {
+		t.Errorf("cpu_count = %d, want > 0", info.CPUCount)
+	}
+}
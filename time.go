@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+type TimeResponse struct {
+	UTC         string `json:"utc"`
+	Unix        int64  `json:"unix"`
+	UnixMS      int64  `json:"unix_ms"`
+	Timezone    string `json:"timezone"`
+	MonotonicNS int64  `json:"monotonic_ns"`
+}
+
+// timeHandler reports the wall clock for comparing skew between pods.
+// monotonic_ns counts from process start on the monotonic clock, so unlike
+// the wall-clock fields it never jumps when NTP steps the time.
+// ?format=rfc1123 returns the time as an HTTP Date header would carry it.
+func timeHandler(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+
+	switch r.URL.Query().Get("format") {
+	case "":
+	case "rfc1123":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, now.UTC().Format(http.TimeFormat))
+		return
+	default:
+		http.Error(w, "format must be rfc1123", http.StatusBadRequest)
+		return
+	}
+
+	timezone := os.Getenv("TZ")
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	writeJSON(w, http.StatusOK, TimeResponse{
+		UTC:         now.UTC().Format(time.RFC3339Nano),
+		Unix:        now.Unix(),
+		UnixMS:      now.UnixMilli(),
+		Timezone:    timezone,
+		MonotonicNS: now.Sub(startTime).Nanoseconds(),
+	})
+}
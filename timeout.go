@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"time"
+)
+
+const timeoutBody = `{"error":"request timeout"}`
+
+// timeoutResponseWriter labels the body written by http.TimeoutHandler on
+// timeout as JSON; responses from the wrapped handler keep their own headers.
+// TimeoutHandler replaces outer header values with the handler's, so Vary
+// entries set by earlier middleware are merged back in.
+type timeoutResponseWriter struct {
+	http.ResponseWriter
+	vary []string
+}
+
+func (w timeoutResponseWriter) WriteHeader(statusCode int) {
+	vary := w.vary
+	for _, value := range w.Header().Values("Vary") {
+		if !slices.Contains(vary, value) {
+			vary = append(vary, value)
+		}
+	}
+	if len(vary) > 0 {
+		w.Header()["Vary"] = vary
+	}
+	if statusCode == http.StatusServiceUnavailable && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w timeoutResponseWriter) Push(target string, opts *http.PushOptions) error {
+	return push(w.ResponseWriter, target, opts)
+}
+
+func WrapWithTimeout(h http.Handler, timeout time.Duration) http.Handler {
+	th := http.TimeoutHandler(h, timeout, timeoutBody)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		vary := append([]string(nil), w.Header().Values("Vary")...)
+		th.ServeHTTP(timeoutResponseWriter{ResponseWriter: w, vary: vary}, r)
+	})
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"time"
+)
+
+type UploadPart struct {
+	Name     string `json:"name"`
+	Filename string `json:"filename,omitempty"`
+	Bytes    int64  `json:"bytes"`
+}
+
+type UploadResponse struct {
+	BytesReceived  int64        `json:"bytes_received"`
+	DurationMS     float64      `json:"duration_ms"`
+	ThroughputMbps float64      `json:"throughput_mbps"`
+	Files          int          `json:"files,omitempty"`
+	Parts          []UploadPart `json:"parts,omitempty"`
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// uploadHandler reads and discards the request body. Multipart bodies are
+// walked part by part instead of going through ParseMultipartForm, which
+// would spill large files to temporary files on disk.
+func uploadHandler(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	body := &countingReader{r: r.Body}
+	r.Body = io.NopCloser(body)
+
+	var response UploadResponse
+	var err error
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		response.Parts, response.Files, err = discardMultipart(r)
+	} else {
+		_, err = io.Copy(io.Discard, r.Body)
+	}
+	if isBodyTooLarge(err) {
+		writeBodyTooLarge(w)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error reading request body", http.StatusBadRequest)
+		return
+	}
+
+	elapsed := time.Since(start)
+	response.BytesReceived = body.n
+	response.DurationMS = float64(elapsed.Microseconds()) / 1000
+	if elapsed > 0 {
+		response.ThroughputMbps = float64(body.n*8) / elapsed.Seconds() / 1e6
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func discardMultipart(r *http.Request) ([]UploadPart, int, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	parts := []UploadPart{}
+	files := 0
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return parts, files, nil
+		}
+		if err != nil {
+			return parts, files, err
+		}
+
+		n, err := io.Copy(io.Discard, part)
+		if err != nil {
+			return parts, files, err
+		}
+		if part.FileName() != "" {
+			files++
+		}
+		parts = append(parts, UploadPart{Name: part.FormName(), Filename: part.FileName(), Bytes: n})
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"runtime"
+)
+
+// Set at build time with
+// -ldflags "-X main.Version=1.2.3 -X main.Commit=abc123 -X main.BuildDate=2024-01-01".
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildDate = "unknown"
+)
+
+// buildVersion returns the VERSION environment variable, else the Version
+// stamped into the binary. ok is false when neither was set and the value is
+// only the "dev" default.
+func buildVersion() (version string, ok bool) {
+	if v := os.Getenv("VERSION"); v != "" {
+		return v, true
+	}
+	return Version, Version != "dev"
+}
+
+// buildCommit is buildVersion for GIT_COMMIT and Commit.
+func buildCommit() (commit string, ok bool) {
+	if c := os.Getenv("GIT_COMMIT"); c != "" {
+		return c, true
+	}
+	return Commit, Commit != "unknown"
+}
+
+type VersionResponse struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+	GoArch    string `json:"go_arch"`
+}
+
+func versionHandler(w http.ResponseWriter, r *http.Request) {
+	version, _ := buildVersion()
+	commit, _ := buildCommit()
+	writeJSON(w, http.StatusOK, VersionResponse{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+		GoArch:    runtime.GOARCH,
+	})
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBuildVersionSources(t *testing.T) {
+	tests := []struct {
+		name        string
+		version     string // stamped with -ldflags
+		commit      string
+		env         map[string]string
+		wantInfo    [2]string // build_version and build_commit in /info
+		wantVersion [2]string // version and commit in /version
+	}{
+		{
+			name:        "nothing injected",
+			version:     "dev",
+			commit:      "unknown",
+			wantInfo:    [2]string{"", ""},
+			wantVersion: [2]string{"dev", "unknown"},
+		},
+		{
+			name:        "ldflags",
+			version:     "1.2.3",
+			commit:      "abc123",
+			wantInfo:    [2]string{"1.2.3", "abc123"},
+			wantVersion: [2]string{"1.2.3", "abc123"},
+		},
+		{
+			name:        "environment over ldflags",
+			version:     "1.2.3",
+			commit:      "unknown",
+			env:         map[string]string{"VERSION": "2.0.0", "GIT_COMMIT": "def456"},
+			wantInfo:    [2]string{"2.0.0", "def456"},
+			wantVersion: [2]string{"2.0.0", "def456"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previousVersion, previousCommit := Version, Commit
+			Version, Commit = tt.version, tt.commit
+			defer func() { Version, Commit = previousVersion, previousCommit }()
+			t.Setenv("VERSION", tt.env["VERSION"])
+			t.Setenv("GIT_COMMIT", tt.env["GIT_COMMIT"])
+
+			info := getBuildInfo()
+			if got := [2]string{info.BuildVersion, info.BuildCommit}; got != tt.wantInfo {
+				t.Errorf("/info build_version, build_commit = %q, want %q", got, tt.wantInfo)
+			}
+
+			w := httptest.NewRecorder()
+			versionHandler(w, httptest.NewRequest(http.MethodGet, "/version", nil))
+			var resp VersionResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if got := [2]string{resp.Version, resp.Commit}; got != tt.wantVersion {
+				t.Errorf("/version version, commit = %q, want %q", got, tt.wantVersion)
+			}
+		})
+	}
+}
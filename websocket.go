@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const wsWriteTimeout = 10 * time.Second
+
+var activeWSClients atomic.Int32
+
+var wsUpgrader = websocket.Upgrader{}
+
+// WSCommand is a message sent by a /ws client.
+type WSCommand struct {
+	Command string `json:"command"`
+}
+
+type WSPong struct {
+	Pong bool   `json:"pong"`
+	Time string `json:"time"`
+}
+
+// websocketHandler sends ServerInfo every interval and answers client
+// commands in between. Connections beyond maxConns are refused with 503
+// before the upgrade.
+func websocketHandler(interval time.Duration, maxConns int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if int(activeWSClients.Add(1)) > maxConns {
+			activeWSClients.Add(-1)
+			writeJSON(w, http.StatusServiceUnavailable, ErrorResponse{Error: "too many websocket connections"})
+			return
+		}
+		defer activeWSClients.Add(-1)
+
+		conn, err := wsUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// Upgrade has already replied with an error status.
+			return
+		}
+		defer conn.Close()
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		// Reads happen on their own goroutine so that all writes stay on this
+		// one, as the connection requires. A read error means the client has
+		// gone away.
+		commands := make(chan WSCommand)
+		go func() {
+			defer cancel()
+			for {
+				_, data, err := conn.ReadMessage()
+				if err != nil {
+					return
+				}
+				var cmd WSCommand
+				_ = json.Unmarshal(data, &cmd)
+				select {
+				case commands <- cmd:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+		send := func(v any) bool {
+			_ = conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			return conn.WriteJSON(v) == nil
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		if !send(getServerInfo()) {
+			return
+		}
+		for {
+			var ok bool
+			select {
+			case <-ticker.C:
+				ok = send(getServerInfo())
+			case cmd := <-commands:
+				if cmd.Command == "ping" {
+					ok = send(WSPong{Pong: true, Time: time.Now().UTC().Format(time.RFC3339Nano)})
+				} else {
+					ok = send(ErrorResponse{Error: "unknown command"})
+				}
+			case <-ctx.Done():
+				return
+			}
+			if !ok {
+				return
+			}
+		}
+	}
+}
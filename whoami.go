@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strings"
+)
+
+type WhoamiResponse struct {
+	Hostname       string            `json:"hostname"`
+	PodName        string            `json:"pod_name,omitempty"`
+	Namespace      string            `json:"namespace,omitempty"`
+	ServiceAccount string            `json:"service_account,omitempty"`
+	IPAddress      string            `json:"ip_address"`
+	Labels         map[string]string `json:"labels"`
+	Annotations    map[string]string `json:"annotations"`
+}
+
+// parseKVList parses "key1=val1,key2=val2". Only the first "=" separates the
+// key from the value, so values may themselves contain "=".
+func parseKVList(s string) map[string]string {
+	kv := map[string]string{}
+	for _, item := range splitList(s) {
+		key, value, _ := strings.Cut(item, "=")
+		if key = strings.TrimSpace(key); key != "" {
+			kv[key] = strings.TrimSpace(value)
+		}
+	}
+	return kv
+}
+
+func whoamiHandler(w http.ResponseWriter, r *http.Request) {
+	metadata := getKubernetesMetadata()
+
+	writeJSON(w, http.StatusOK, WhoamiResponse{
+		Hostname:       getHostname(),
+		PodName:        metadata.PodName,
+		Namespace:      metadata.Namespace,
+		ServiceAccount: metadata.ServiceAccount,
+		IPAddress:      primaryIPAddress(getAllInterfaces(), "ipv4"),
+		Labels:         parseKVList(os.Getenv("LABELS")),
+		Annotations:    parseKVList(os.Getenv("ANNOTATIONS")),
+	})
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseKVList(t *testing.T) {
+	tests := []struct {
+		in   string
+		want map[string]string
+	}{
+		{"", map[string]string{}},
+		{"app=web", map[string]string{"app": "web"}},
+		{"app=web,tier=frontend", map[string]string{"app": "web", "tier": "frontend"}},
+		{" app = web , tier=frontend ", map[string]string{"app": "web", "tier": "frontend"}},
+		{"query=a=b&c=d", map[string]string{"query": "a=b&c=d"}},
+		{"token==", map[string]string{"token": "="}},
+		{"empty=", map[string]string{"empty": ""}},
+		{"flag", map[string]string{"flag": ""}},
+		{"=orphan,,app=web,", map[string]string{"app": "web"}},
+		{"app=web,app=api", map[string]string{"app": "api"}},
+	}
+	for _, tt := range tests {
+		if got := parseKVList(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseKVList(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}